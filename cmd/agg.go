@@ -0,0 +1,133 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AggSum is sum of values.
+	AggSum = "sum"
+	// AggCount is count of values.
+	AggCount = "count"
+	// AggMin is minimum value.
+	AggMin = "min"
+	// AggMax is maximum value.
+	AggMax = "max"
+	// AggAvg is average of values.
+	AggAvg = "avg"
+	// AggFirst is the first value.
+	AggFirst = "first"
+	// AggLast is the last value.
+	AggLast = "last"
+	// AggConcat is values joined with concatSep.
+	AggConcat = "concat"
+
+	// concatSep is separator of concat values.
+	concatSep = ";"
+)
+
+// aggNames is aggregation function names.
+var aggNames = []string{AggSum, AggCount, AggMin, AggMax, AggAvg, AggFirst, AggLast, AggConcat}
+
+// aggregator aggregates values of a key in a csv.
+type aggregator struct {
+	name   string
+	count  int
+	n      float64
+	first  string
+	last   string
+	values []string
+}
+
+// validateAgg checks aggregation function name.
+func validateAgg(name string) error {
+	for _, n := range aggNames {
+		if n == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown aggregation. [%s] (%s)", name, strings.Join(aggNames, ", "))
+}
+
+// isNumericAgg returns whether aggregation needs numeric values.
+func isNumericAgg(name string) bool {
+	switch name {
+	case AggSum, AggMin, AggMax, AggAvg:
+		return true
+	}
+	return false
+}
+
+// aggKind returns value kind of aggregated values of kind.
+func aggKind(name string, kind ValueKind) ValueKind {
+	switch {
+	case name == AggCount, isNumericAgg(name):
+		return NumberKind
+	case name == AggConcat:
+		return StringKind
+	}
+	return kind
+}
+
+// add adds value. Error if value is not a number for numeric aggregation.
+func (a *aggregator) add(value string) error {
+	if isNumericAgg(a.name) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("Not a number for %s. [%s]", a.name, value)
+		}
+		switch {
+		case a.count == 0:
+			a.n = v
+		case a.name == AggSum, a.name == AggAvg:
+			a.n += v
+		case a.name == AggMin && v < a.n, a.name == AggMax && v > a.n:
+			a.n = v
+		}
+	}
+	if a.count == 0 {
+		a.first = value
+	}
+	a.last = value
+	if a.name == AggConcat {
+		a.values = append(a.values, value)
+	}
+	a.count++
+	return nil
+}
+
+// result returns aggregated value. Empty if no values.
+func (a *aggregator) result() string {
+	if a == nil || a.count == 0 {
+		return ""
+	}
+	switch a.name {
+	case AggSum, AggMin, AggMax:
+		return strconv.FormatFloat(a.n, 'f', -1, 64)
+	case AggAvg:
+		return strconv.FormatFloat(a.n/float64(a.count), 'f', -1, 64)
+	case AggCount:
+		return fmt.Sprint(a.count)
+	case AggFirst:
+		return a.first
+	case AggConcat:
+		return strings.Join(a.values, concatSep)
+	}
+	return a.last
+}
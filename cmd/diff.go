@@ -17,13 +17,16 @@ package cmd
 import (
 	"encoding/csv"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
@@ -38,16 +41,22 @@ const (
 )
 
 var (
-	sjisIn bool
+	sjisIn        bool
+	content       bool
+	ignoreTime    bool
+	dstTolerance  bool
+	timeTolerance time.Duration
+
+	// liveHashes caches hashes of live files read by content diff.
+	liveHashes = struct {
+		sync.Mutex
+		m map[string]string
+	}{m: make(map[string]string)}
 )
 
 type info struct {
-	path  string
-	index int
-	rel  string
 	diff  FileInfoValue
 	value string
-	ford  string
 }
 
 // diffCmd represents the diff command
@@ -67,25 +76,34 @@ func init() {
 	RootCmd.AddCommand(diffCmd)
 
 	// Sort with target column for csv.
-	diffCmd.Flags().StringVarP(&sorts, "sorts", "s", "0,2", "Sort target column number with commma sepalated (ex: 1,2,0)")
+	diffCmd.Flags().StringVarP(&sorts, "sorts", "s", "0,2", "Sort columns with comma separated. Column number or name with :num, :size, :time, :str or :desc (ex: 2,Path)")
 	// Whether input csv in ShiftJIS encoding.
 	diffCmd.Flags().BoolVarP(&sjisIn, "sjisin", "J", false, "Input csv in ShiftJIS encoding")
+	// Whether compare live file contents.
+	diffCmd.Flags().BoolVarP(&content, "content", "c", false, "Compare contents of files on Full path when csv has no hash")
+	// Skip flag.
+	diffCmd.Flags().BoolVarP(&errSkip, "err", "e", false, "Skip reading file contents on error")
+	// Time comparison.
+	diffCmd.Flags().BoolVar(&ignoreTime, "ignore-time", false, "Ignore modified time difference")
+	diffCmd.Flags().DurationVar(&timeTolerance, "time-tolerance", 0, "Times within this window are the same (ex: 2s for FAT, SMB)")
+	diffCmd.Flags().BoolVar(&dstTolerance, "dst-tolerance", false, "Times shifted by just one hour (within time tolerance) are the same")
 }
 
 func executeDiff(cmd *cobra.Command, args []string) {
 
 	var (
-		err error
+		err  error
+		keys []sortKey
 
-		match  *regexp.Regexp
-		ignore *regexp.Regexp
+		match    *regexp.Regexp
+		ignore   *regexp.Regexp
+		csvArray records
 
-		csvMap  = make(map[string][]string)
-		fisList = make([]FileInfos, 0)
-		q       = make(chan info)
-		wg      = new(sync.WaitGroup)
+		snapshots = make([]*snapshot, 0)
 	)
 
+	cnt = 0
+
 	if len(args) == 0 {
 		cmd.Help()
 		return
@@ -103,37 +121,24 @@ func executeDiff(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// Load csv and store.
-	for _, csvPath := range args {
-		fmt.Println("Open:", csvPath)
-		c, err := os.Open(csvPath)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		defer c.Close()
-		var reader *csv.Reader
-		if sjisIn {
-			reader = csv.NewReader(transform.NewReader(c, japanese.ShiftJIS.NewDecoder()))
-		} else {
-			reader = csv.NewReader(c)
-		}
-		reader.Comma = ','
-		// Skip header.
-		_, err = reader.Read()
+	// Check sort keys. Value columns are sorted as strings unless typed.
+	header := append(strings.Split(DiffHeader, "\t"), args...)
+	customSort := cmd.Flags().Changed("sorts") && sorts != ""
+	if customSort {
+		keys, err = parseSortKeys(sorts, header, func(int) ValueKind { return StringKind })
 		if err != nil {
 			log.Fatalln(err)
 		}
-		left, err := reader.ReadAll()
+	}
+
+	// Load csv and store.
+	for _, csvPath := range args {
+		fmt.Fprintln(os.Stderr, "Open:", csvPath)
+		fis, err := readFileInfos(csvPath)
 		if err != nil {
 			log.Fatalln(err)
 		}
-
-		// Change data to FileInfos struct.
-		fis := make(FileInfos, 0)
-		for _, r := range left {
-			fis = append(fis, *csvToFileInfo(r))
-		}
-		fisList = append(fisList, fis)
+		snapshots = append(snapshots, newSnapshot(fis))
 	}
 
 	// Compile if given matches and ignores.
@@ -150,179 +155,535 @@ func executeDiff(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	for i, one := range fisList {
-		wg.Add(1)
-		go func(i int, one FileInfos) {
-			defer wg.Done()
+	filter := func(fi FileInfo) bool {
+		if fileOnly && fi.Type != FILE {
+			return false
+		}
+		if dirOnly && fi.Type != DIR {
+			return false
+		}
+		// Ignore check.
+		if ignore != nil && ignore.MatchString(fi.Full) {
+			return false
+		}
+		// Match check.
+		if match != nil && !match.MatchString(fi.Full) {
+			return false
+		}
+		return true
+	}
+	// Range check. Unparsable time is out of time range.
+	inRange := func(fi FileInfo) bool {
+		var mtime time.Time
+		if !filterNewer.IsZero() || !filterOlder.IsZero() {
+			mtime, _ = parseTime(fi.Time)
+		}
+		return passEntry(fi.Type, fi.Size, fi.DiskUsage, mtime)
+	}
 
-			// Diff fileinfo.
-			for _, oneFi := range one {
-				if fileOnly && oneFi.Type == DIR {
-					continue
-				}
-				if dirOnly && oneFi.Type == FILE {
-					continue
-				}
+	// Output is created when the first difference is found.
+	writer := newOutput(out, header, diffKinds)
+	write := func(record []string) {
+		err := writer.Write(record)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
 
-				// Ignore check.
-				if ignore != nil && ignore.MatchString(oneFi.Full) {
-					continue
-				}
+	// Rows are streamed in path and diff order unless sort columns are given.
+	diffSnapshots(snapshots, filter, inRange, func(row []string) {
+		cnt++
+		if !silent {
+			fmt.Fprintf(os.Stderr, "Count: %d\r", cnt)
+		}
+		if customSort {
+			csvArray = append(csvArray, row)
+		} else {
+			write(row)
+		}
+	})
 
-				// Match check.
-				if match != nil && !match.MatchString(oneFi.Full) {
-					continue
-				}
+	if cnt == 0 {
+		fmt.Fprintln(os.Stderr, "There is no difference !")
+		return
+	}
 
-				for j, other := range fisList {
-					if i == j {
-						continue
-					}
-
-					// Get other's same rel path info.
-					otherFi, err := findFileInfo(other, oneFi)
-					if err == nil {
-						// Diff Time.
-						if oneFi.Time != otherFi.Time {
-							q <- info{
-								path:  args[i],
-								index: i,
-								rel:  oneFi.Rel,
-								diff:  FileTime,
-								value: oneFi.Time,
-								ford:  oneFi.Type,
-							}
-						}
-						// Diff Size.
-						if oneFi.Size != otherFi.Size {
-							q <- info{
-								path:  args[i],
-								index: i,
-								rel:  oneFi.Rel,
-								diff:  FileSize,
-								value: oneFi.Size,
-								ford:  oneFi.Type,
-							}
-						}
-						// Diff Mode.
-						if oneFi.Mode != otherFi.Mode {
-							q <- info{
-								path:  args[i],
-								index: i,
-								rel:  oneFi.Rel,
-								diff:  FileMode,
-								value: oneFi.Mode,
-								ford:  oneFi.Type,
-							}
-						}
-					} else {
-						q <- info{
-							path:  args[i],
-							index: i,
-							rel:  oneFi.Rel,
-							diff:  FileFull,
-							value: oneFi.Rel,
-							ford:  oneFi.Type,
-						}
-					}
+	if customSort {
+		sortRecords(csvArray, keys)
+		for _, v := range csvArray {
+			write(v)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Fprintf(os.Stderr, "Write to [%s]. ([%d] row)\n", outName(), cnt)
+}
+
+// diffKinds returns value kind of diff row. Values are typed by Diff column.
+func diffKinds(record []string, i int) ValueKind {
+	if i < 3 {
+		return StringKind
+	}
+	return kindOf(record[2])
+}
+
+// snapshot is FileInfos of one csv indexed by Rel.
+type snapshot struct {
+	fis   FileInfos
+	index map[string]int
+}
+
+func newSnapshot(fis FileInfos) *snapshot {
+	index := make(map[string]int, len(fis))
+	for i, fi := range fis {
+		index[fi.Rel] = i
+	}
+	return &snapshot{fis: fis, index: index}
+}
+
+// find returns FileInfo of rel path.
+func (s *snapshot) find(rel string) (FileInfo, bool) {
+	i, ok := s.index[rel]
+	if !ok {
+		return FileInfo{}, false
+	}
+	return s.fis[i], true
+}
+
+// diffSnapshots compares each snapshot with the others and emits diff rows
+// ordered by rel path and diff type.
+// Rel path is compared if it is in range of any snapshot, so files crossing the range are not added or deleted.
+func diffSnapshots(snapshots []*snapshot, filter, inRange func(FileInfo) bool, emit func([]string)) {
+
+	// Pair renamed files of each snapshot.
+	renames := make([][]map[string]string, len(snapshots))
+	for i := range snapshots {
+		renames[i] = make([]map[string]string, len(snapshots))
+	}
+	for i := range snapshots {
+		for j := i + 1; j < len(snapshots); j++ {
+			renames[i][j], renames[j][i] = findRenames(snapshots[i], snapshots[j])
+		}
+	}
+
+	// Sorted rel paths of all snapshots.
+	rels := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, s := range snapshots {
+		for _, fi := range s.fis {
+			if !seen[fi.Rel] {
+				seen[fi.Rel] = true
+				rels = append(rels, fi.Rel)
+			}
+		}
+	}
+	sort.Strings(rels)
+
+	for _, rel := range rels {
+		if !anyInRange(snapshots, rel, inRange) {
+			continue
+		}
+		for _, row := range diffRel(snapshots, renames, rel, filter) {
+			emit(row)
+		}
+	}
+}
+
+// anyInRange returns whether FileInfo of rel path is in range in any of snapshots.
+func anyInRange(snapshots []*snapshot, rel string, inRange func(FileInfo) bool) bool {
+	for _, s := range snapshots {
+		if fi, ok := s.find(rel); ok && inRange(fi) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffRel returns diff rows of rel path ordered by diff type.
+func diffRel(snapshots []*snapshot, renames [][]map[string]string, rel string, filter func(FileInfo) bool) [][]string {
+
+	rows := make(map[FileInfoValue][]string)
+	set := func(diff FileInfoValue, ford string, index int, value string) {
+		row, ok := rows[diff]
+		if !ok {
+			row = make([]string, len(snapshots)+3)
+			row[0] = rel
+			row[1] = ford
+			row[2] = diff.String()
+			rows[diff] = row
+		}
+		row[index+3] = value
+	}
+
+	for i, one := range snapshots {
+		oneFi, ok := one.find(rel)
+		if !ok || !filter(oneFi) {
+			continue
+		}
+		for j, other := range snapshots {
+			if i == j {
+				continue
+			}
+
+			// Get other's same rel path info.
+			if otherFi, ok := other.find(rel); ok {
+				for _, d := range compareFileInfo(oneFi, otherFi) {
+					set(d.diff, oneFi.Type, i, d.value)
+				}
+			} else if newRel, ok := renames[i][j][rel]; ok {
+				// Output renamed from former csv only.
+				if i < j {
+					set(FileRenamed, oneFi.Type, i, rel)
+					set(FileRenamed, oneFi.Type, j, newRel)
 				}
+			} else {
+				set(FileFull, oneFi.Type, i, rel)
 			}
-		}(i, one)
+		}
 	}
 
-	// Async wait.
-	go func() {
-		wg.Wait()
-		close(q)
-	}()
+	diffs := make([]int, 0, len(rows))
+	for diff := range rows {
+		diffs = append(diffs, int(diff))
+	}
+	sort.Ints(diffs)
+	sorted := make([][]string, 0, len(diffs))
+	for _, diff := range diffs {
+		sorted = append(sorted, rows[FileInfoValue(diff)])
+	}
+	return sorted
+}
 
-	// Receive diff and store to array.
-	for info := range q {
-		cnt++
-		if !silent {
-			fmt.Fprintf(os.Stderr, "Count: %d\r", cnt)
+// compareFileInfo returns diff types and one's values which differ from other.
+func compareFileInfo(one, other FileInfo) []info {
+
+	diffs := make([]info, 0)
+
+	// Diff Time as instants.
+	if !ignoreTime && one.Time != other.Time && (one.Time == "" || other.Time == "" || differTime(one.Time, other.Time)) {
+		diffs = append(diffs, info{diff: FileTime, value: one.Time})
+	}
+	// Diff Size.
+	if one.Size != other.Size {
+		diffs = append(diffs, info{diff: FileSize, value: one.Size})
+	}
+	// Diff Mode.
+	if one.Mode != other.Mode {
+		diffs = append(diffs, info{diff: FileMode, value: one.Mode})
+	}
+	// Diff Owner and Group.
+	if differOwner(one.Owner, other.Owner, one.UID, other.UID) {
+		diffs = append(diffs, info{diff: FileOwner, value: firstValue(one.Owner, one.UID)})
+	}
+	if differOwner(one.Group, other.Group, one.GID, other.GID) {
+		diffs = append(diffs, info{diff: FileGroup, value: firstValue(one.Group, one.GID)})
+	}
+	// Diff extended times if both have.
+	for _, fiv := range []FileInfoValue{FileATime, FileCTime, FileBTime} {
+		if differTime(one.value(fiv), other.value(fiv)) {
+			diffs = append(diffs, info{diff: fiv, value: one.value(fiv)})
 		}
-		key := info.rel + fmt.Sprint(info.diff)
-		if _, ok := csvMap[key]; ok {
-			csvMap[key][info.index+3] = info.value
-		} else {
-			s := make([]string, len(args)+3)
-			s[0] = info.rel
-			s[1] = info.ford
-			s[2] = fmt.Sprint(info.diff)
-			s[info.index+3] = info.value
-			csvMap[key] = s
+	}
+	// Diff symbolic link if both have.
+	for _, fiv := range []FileInfoValue{FileLinkTarget, FileBroken} {
+		if differ(one.value(fiv), other.value(fiv)) {
+			diffs = append(diffs, info{diff: fiv, value: one.value(fiv)})
+		}
+	}
+	// Diff Hash.
+	if one.Type == FILE && other.Type == FILE {
+		oneHash, otherHash, ok := commonHash(one, other)
+		if !ok && content && one.Size == other.Size {
+			oneHash, otherHash, ok = contentHash(one, other)
+		}
+		if ok && oneHash != otherHash {
+			diffs = append(diffs, info{diff: FileHash, value: oneHash})
 		}
 	}
+	return diffs
+}
 
-	if len(csvMap) == 0 {
-		fmt.Println("There is no difference !")
-		return
+// differ returns whether both values exist and differ.
+func differ(one, other string) bool {
+	return one != "" && other != "" && one != other
+}
+
+// differOwner returns whether owner (or group) differs.
+// Names are compared if both have names, otherwise ids. A numeric name is an id. (older csv)
+func differOwner(oneName, otherName, oneID, otherID string) bool {
+	if oneName != "" && otherName != "" && isID(oneName) == isID(otherName) {
+		return oneName != otherName
+	}
+	if isID(oneName) {
+		oneID = firstValue(oneID, oneName)
+	}
+	if isID(otherName) {
+		otherID = firstValue(otherID, otherName)
+	}
+	return differ(oneID, otherID)
+}
+
+// isID returns whether s is a numeric id.
+func isID(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
+
+// firstValue returns the first non empty value.
+func firstValue(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// commonHash returns hashes of the first algorithm both one and other have.
+func commonHash(one, other FileInfo) (string, string, bool) {
+	for _, fiv := range hashPriority {
+		oneHash, otherHash := one.value(fiv), other.value(fiv)
+		if oneHash != "" && otherHash != "" {
+			return oneHash, otherHash, true
+		}
 	}
+	return "", "", false
+}
 
-	// Output to csv.
-	os.MkdirAll(filepath.Dir(out), os.ModePerm)
-	c, err := os.Create(out)
+// contentHash returns hashes of live files on one and other's Full path.
+func contentHash(one, other FileInfo) (string, string, bool) {
+	oneHash, err := liveHash(one.Full)
 	if err != nil {
-		log.Fatalln(err)
+		if !errSkip {
+			log.Fatalln(err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+		return "", "", false
 	}
-	defer c.Close()
-	var writer *csv.Writer
-	if sjisOut {
-		writer = csv.NewWriter(transform.NewWriter(c, japanese.ShiftJIS.NewEncoder()))
-	} else {
-		writer = csv.NewWriter(c)
+	otherHash, err := liveHash(other.Full)
+	if err != nil {
+		if !errSkip {
+			log.Fatalln(err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+		return "", "", false
 	}
-	writer.Comma = ','
-	writer.UseCRLF = true
+	return oneHash, otherHash, true
+}
 
-	// Write header.
-	err = writer.Write(append(strings.Split(DiffHeader, "\t"), args...))
+// liveHash returns xxHash of file contents on path.
+func liveHash(path string) (string, error) {
+	liveHashes.Lock()
+	sum, ok := liveHashes.m[path]
+	liveHashes.Unlock()
+	if ok {
+		return sum, nil
+	}
+
+	sums, err := hashFile(path, []string{XXHASH})
 	if err != nil {
-		log.Fatalln(err)
+		return "", err
 	}
+	liveHashes.Lock()
+	liveHashes.m[path] = sums[XXHASH]
+	liveHashes.Unlock()
+	return sums[XXHASH], nil
+}
 
-	// map to array.
-	var csvArray records
-	for _, v := range csvMap {
-		csvArray = append(csvArray, v)
+// findRenames pairs files only in one with files only in other.
+// Files are paired by hash, or by name, size and time if no hash.
+// Returns one's rel to other's rel, and other's rel to one's rel.
+func findRenames(one, other *snapshot) (map[string]string, map[string]string) {
+
+	var (
+		oneToOther = make(map[string]string)
+		otherToOne = make(map[string]string)
+		fiv        = commonHashColumn(one.fis, other.fis)
+	)
+
+	oneOnly := renameCandidates(one, other, fiv)
+	otherOnly := renameCandidates(other, one, fiv)
+	for key, oneFis := range oneOnly {
+		otherFis, ok := otherOnly[key]
+		// Skip ambiguous pair.
+		if !ok || len(oneFis) != 1 || len(otherFis) != 1 {
+			continue
+		}
+		oneToOther[oneFis[0].Rel] = otherFis[0].Rel
+		otherToOne[otherFis[0].Rel] = oneFis[0].Rel
 	}
+	return oneToOther, otherToOne
+}
 
-	// sort
-	if sorts == "" {
-		sorts = "0,2"
+// renameCandidates returns files only in fis grouped by rename key.
+func renameCandidates(s, other *snapshot, fiv FileInfoValue) map[string]FileInfos {
+	candidates := make(map[string]FileInfos)
+	for _, fi := range s.fis {
+		if fi.Type != FILE {
+			continue
+		}
+		if _, ok := other.find(fi.Rel); ok {
+			continue
+		}
+		key := fi.Name + "\t" + fi.Size + "\t" + timeKey(fi.Time)
+		if fiv != 0 {
+			key = fi.value(fiv)
+		}
+		if key == "" {
+			continue
+		}
+		candidates[key] = append(candidates[key], fi)
+	}
+	return candidates
+}
+
+// commonHashColumn returns the first hash column both one and other have. 0 if none.
+func commonHashColumn(one, other FileInfos) FileInfoValue {
+	has := func(fis FileInfos, fiv FileInfoValue) bool {
+		for _, fi := range fis {
+			if fi.value(fiv) != "" {
+				return true
+			}
+		}
+		return false
+	}
+	for _, fiv := range hashPriority {
+		if has(one, fiv) && has(other, fiv) {
+			return fiv
+		}
 	}
-	sort.Sort(csvArray)
+	return 0
+}
+
+// readFileInfos reads csv output of get command.
+// Columns are mapped by header name, so extra columns are ignored and missing columns are empty.
+// Csv without format marker line is of version 1 and mapped by the fixed 8 columns.
+func readFileInfos(csvPath string) (FileInfos, error) {
+
+	fis := make(FileInfos, 0)
 
-	for _, v := range csvArray {
-		err = writer.Write(v)
+	c, err := os.Open(csvPath)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var reader *csv.Reader
+	if sjisIn {
+		reader = csv.NewReader(transform.NewReader(c, japanese.ShiftJIS.NewDecoder()))
+	} else {
+		reader = csv.NewReader(c)
+	}
+	reader.Comma = ','
+	// Format marker line has one field.
+	reader.FieldsPerRecord = -1
+
+	// Read format marker and header for columns.
+	version := 1
+	header, err := reader.Read()
+	if err == nil && len(header) == 1 && strings.HasPrefix(header[0], formatMarker) {
+		version, err = formatVersion(header[0])
 		if err != nil {
-			log.Fatalln(err)
+			return nil, fmt.Errorf("%s [%s]", err, csvPath)
 		}
+		header, err = reader.Read()
+	}
+	if err == io.EOF {
+		return nil, fmt.Errorf("Empty csv. [%s]", csvPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read header. [%s] (%s)", csvPath, err)
+	}
+	var cols map[FileInfoValue]int
+	if version == 1 {
+		cols, err = legacyColumns(header)
+		if err != nil {
+			return nil, fmt.Errorf("%s [%s]", err, csvPath)
+		}
+	} else {
+		cols = csvColumns(header)
+		if _, ok := cols[FileRel]; !ok {
+			return nil, fmt.Errorf("Not a csv of gfi get. Column [%s] is not found. [%s]", FileRel, csvPath)
+		}
+	}
+
+	for {
+		r, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read. [%s] (%s)", csvPath, err)
+		}
+		fi := csvToFileInfo(cols, r)
+		fillFileInfo(fi)
+		fis = append(fis, *fi)
+	}
+	return fis, nil
+}
+
+// fillFileInfo fills path values missing in csv of selected columns.
+func fillFileInfo(fi *FileInfo) {
+	if fi.Name == "" {
+		fi.Name = filepath.Base(fi.Rel)
+	}
+	if fi.Full == "" {
+		fi.Full = fi.Abs
+	}
+	if fi.Full == "" {
+		fi.Full = fi.Rel
+	}
+	if fi.Abs == "" {
+		fi.Abs = fi.Full
 	}
-	writer.Flush()
-	fmt.Printf("Write to [%s]. ([%d] row)\n", out, cnt)
 }
 
-func findFileInfo(fis FileInfos, target FileInfo) (FileInfo, error) {
+// formatVersion returns format version of marker line.
+func formatVersion(marker string) (int, error) {
+	v := strings.TrimPrefix(marker, formatMarker)
+	version, err := strconv.Atoi(v)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("Invalid format version. [%s]", v)
+	}
+	if version > FormatVersion {
+		return 0, fmt.Errorf("Unsupported format version. [%d] (<= %d)", version, FormatVersion)
+	}
+	return version, nil
+}
 
-	for _, fi := range fis {
-		if fi.Rel == target.Rel {
-			return fi, nil
+// legacyColumns returns indexes of the fixed 8 columns of version 1.
+func legacyColumns(header []string) (map[FileInfoValue]int, error) {
+	var fiv FileInfoValue
+	cols := make(map[FileInfoValue]int)
+	for fiv = FileFull; fiv <= FileType; fiv++ {
+		i := int(fiv) - 1
+		if i >= len(header) || !strings.EqualFold(header[i], fiv.String()) {
+			return nil, fmt.Errorf("Not a csv of gfi get. No format marker and not columns of version 1.")
 		}
+		cols[fiv] = i
 	}
-	return FileInfo{}, fmt.Errorf("Not found. [%s]", target.Full)
+	return cols, nil
 }
 
-func csvToFileInfo(data []string) *FileInfo {
-	return &FileInfo{
-		Full: data[FileFull-1],
-		Rel:  data[FileRel-1],
-		Abs:  data[FileAbs-1],
-		Name: data[FileName-1],
-		Time: data[FileTime-1],
-		Size: data[FileSize-1],
-		Mode: data[FileMode-1],
-		Type: data[FileType-1],
+// csvColumns returns indexes of FileInfo columns by csv header name.
+func csvColumns(header []string) map[FileInfoValue]int {
+	cols := make(map[FileInfoValue]int)
+	for i, h := range header {
+		if fiv := fileColumn(h); fiv != 0 {
+			cols[fiv] = i
+		}
+	}
+	return cols
+}
+
+// csvToFileInfo returns FileInfo of csv data with column indexes.
+func csvToFileInfo(cols map[FileInfoValue]int, data []string) *FileInfo {
+	fi := new(FileInfo)
+	for fiv, i := range cols {
+		if i < len(data) {
+			fi.setValue(fiv, data[i])
+		}
 	}
+	return fi
 }
@@ -16,10 +16,13 @@ package cmd
 
 import (
 	"encoding/csv"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -139,3 +142,540 @@ func TestDiffCmdRun(t *testing.T) {
 	}
 
 }
+
+// TestDiffCmdRunHash is test diffCmd.Run with hash columns and content flag.
+func TestDiffCmdRunHash(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(pwd)
+	defer func() { hashes, content, fileOnly = nil, false, false }()
+
+	// Same size, same modified time, different contents.
+	mtime := time.Now().Add(-time.Hour)
+	snapshot := func(dir, data string, args ...string) (string, string) {
+		err := os.MkdirAll(filepath.Join(tmp, dir), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chdir(filepath.Join(tmp, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile("file0", []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Chtimes("file0", mtime, mtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+		withHash := filepath.Join(tmp, dir+"-hash.csv")
+		RootCmd.SetArgs([]string{"get", "-f", "-H", "sha256", "-o", withHash, "."})
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = nil
+		noHash := filepath.Join(tmp, dir+".csv")
+		RootCmd.SetArgs([]string{"get", "-f", "-o", noHash, "."})
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return withHash, noHash
+	}
+	diff := func(args ...string) [][]string {
+		d := filepath.Join(tmp, diffCsv1)
+		os.Remove(d)
+		RootCmd.SetArgs(append([]string{"diff", "-o", d}, args...))
+		err := RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(d)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	oneHash, one := snapshot("one", "one")
+	otherHash, other := snapshot("other", "two")
+
+	// Csv has hash columns.
+	records := diff(oneHash, otherHash)
+	if len(records) != 2 || records[1][2] != FileHash.String() {
+		t.Fatalf("Expect: [%v] Actual: [%v]", FileHash.String(), records)
+	}
+
+	// Csv has no hash columns.
+	records = diff(one, other)
+	if len(records) != 0 {
+		t.Fatalf("Expect: no difference Actual: [%v]", records)
+	}
+
+	// Read live files.
+	records = diff("-c", one, other)
+	if len(records) != 2 || records[1][2] != FileHash.String() {
+		t.Fatalf("Expect: [%v] Actual: [%v]", FileHash.String(), records)
+	}
+}
+
+// TestDiffCmdRunRenamed is test diffCmd.Run with renamed and moved files.
+func TestDiffCmdRunRenamed(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	outDir, err := ioutil.TempDir("", "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(outDir)
+	defer func() { hashes, fileOnly = nil, false }()
+
+	err = ioutil.WriteFile(filepath.Join(tmp, "file0"), []byte("renamed"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1 := filepath.Join(outDir, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-f", "-H", "md5", "-o", c1, tmp})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rename file0 and move file1.
+	err = os.Rename(filepath.Join(tmp, "file0"), filepath.Join(tmp, "renamed0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Rename(filepath.Join(tmp, "file1"), filepath.Join(tmp, "dir2", "file1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes = nil
+	c2 := filepath.Join(outDir, getCsv2)
+	RootCmd.SetArgs([]string{"get", "-f", "-H", "md5", "-o", c2, tmp})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc1 := filepath.Join(outDir, diffCsv1)
+	RootCmd.SetArgs([]string{"diff", "-o", dc1, c1, c2})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check csv.
+	f, err := os.Open(dc1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := make(map[string]string)
+	for _, r := range records[1:] {
+		if r[2] != FileRenamed.String() {
+			t.Fatalf("Expect: [%v] Actual: [%v]", FileRenamed.String(), r)
+		}
+		renamed[r[3]] = r[4]
+	}
+	expects := map[string]string{
+		filepath.Join(tmp, "file0"): filepath.Join(tmp, "renamed0"),
+		filepath.Join(tmp, "file1"): filepath.Join(tmp, "dir2", "file1"),
+	}
+	for old, new := range expects {
+		if renamed[old] != new {
+			t.Fatalf("Expect: [%v] -> [%v] Actual: [%v]", old, new, renamed)
+		}
+	}
+}
+
+// TestDiffCmdRunSymlink is test diffCmd.Run of snapshots with symbolic links.
+func TestDiffCmdRunSymlink(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Symbolic link needs privilege on windows")
+	}
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	outDir, err := ioutil.TempDir("", "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(outDir)
+	defer func() { fileOnly, dirOnly = false, false }()
+
+	get := func(name string) string {
+		c := filepath.Join(outDir, name)
+		RootCmd.SetArgs([]string{"get", "-o", c, tmp})
+		err := RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	diff := func(args ...string) [][]string {
+		d := filepath.Join(outDir, diffCsv1)
+		os.Remove(d)
+		RootCmd.SetArgs(append([]string{"diff", "-o", d}, args...))
+		err := RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records[1:]
+	}
+	types := func(records [][]string) map[string]bool {
+		m := make(map[string]bool)
+		for _, r := range records {
+			m[r[1]] = true
+		}
+		return m
+	}
+
+	// Links are deleted, and a file and a directory are added.
+	for _, l := range []string{"file0", "dir0"} {
+		err = os.Symlink(filepath.Join(tmp, l), filepath.Join(tmp, "link-"+l))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	c1 := get(getCsv1)
+	for _, l := range []string{"file0", "dir0"} {
+		err = os.Remove(filepath.Join(tmp, "link-"+l))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ioutil.WriteFile(filepath.Join(tmp, "new0"), []byte("new"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(filepath.Join(tmp, "newdir"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2 := get(getCsv2)
+
+	if actual := types(diff(c1, c2)); !actual[SYMLINK] || !actual[FILE] || !actual[DIR] {
+		t.Fatalf("Expect: [%v %v %v] Actual: [%v]", SYMLINK, FILE, DIR, actual)
+	}
+	fileOnly = true
+	if actual := types(diff(c1, c2)); len(actual) != 1 || !actual[FILE] {
+		t.Fatalf("Expect: [%v] Actual: [%v]", FILE, actual)
+	}
+	fileOnly, dirOnly = false, true
+	if actual := types(diff(c1, c2)); len(actual) != 1 || !actual[DIR] {
+		t.Fatalf("Expect: [%v] Actual: [%v]", DIR, actual)
+	}
+}
+
+// TestDiffCmdRunFilter is test diffCmd.Run with size range across snapshots.
+func TestDiffCmdRunFilter(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	outDir, err := ioutil.TempDir("", "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(outDir)
+	defer func() { fileOnly, minSize = false, "" }()
+
+	// grow crosses min size between snapshots.
+	grow := filepath.Join(tmp, "grow")
+	cs := make([]string, 0)
+	for i, size := range []int{10, 100} {
+		err = ioutil.WriteFile(grow, make([]byte, size), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := filepath.Join(outDir, fmt.Sprint("get", i, ".csv"))
+		RootCmd.SetArgs([]string{"get", "-f", "-o", c, tmp})
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs = append(cs, c)
+	}
+
+	d := filepath.Join(outDir, diffCsv1)
+	RootCmd.SetArgs([]string{"diff", "--min-size", "50", "-o", d, cs[0], cs[1]})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs := make(map[string]bool)
+	for _, r := range records[1:] {
+		if filepath.Base(r[0]) != "grow" {
+			t.Fatalf("Expect: [grow] Actual: [%v]", r[0])
+		}
+		diffs[r[2]] = true
+	}
+	if !diffs[FileSize.String()] || diffs[FileFull.String()] {
+		t.Fatalf("Expect: [%v] Actual: [%v]", FileSize.String(), diffs)
+	}
+}
+
+// benchmarkSnapshots returns two snapshots of n files with some differences.
+func benchmarkSnapshots(n int) []*snapshot {
+	one := make(FileInfos, 0, n)
+	other := make(FileInfos, 0, n)
+	for i := 0; i < n; i++ {
+		fi := FileInfo{
+			Rel:  filepath.Join("root", fmt.Sprint("dir", i%100), fmt.Sprint("file", i)),
+			Name: fmt.Sprint("file", i),
+			Time: "2017/03/25 12:00:00.000",
+			Size: fmt.Sprint(i),
+			Mode: "-rw-r--r--",
+			Type: FILE,
+		}
+		one = append(one, fi)
+		switch i % 10 {
+		case 0:
+			fi.Size = fmt.Sprint(i + 1)
+		case 1:
+			fi.Rel += ".new"
+		}
+		other = append(other, fi)
+	}
+	return []*snapshot{newSnapshot(one), newSnapshot(other)}
+}
+
+// BenchmarkDiffSnapshots is benchmark of diffSnapshots by number of files.
+func BenchmarkDiffSnapshots(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		snapshots := benchmarkSnapshots(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				diffSnapshots(snapshots, func(FileInfo) bool { return true }, func(FileInfo) bool { return true }, func([]string) {})
+			}
+		})
+	}
+}
+
+// TestDiffSnapshotsScaling is test diffSnapshots time per row is near linear.
+// Time per row of 100000 rows must be within 3 times of 10000 rows. (O(n^2) is 10 times)
+// Wall clock timing is flaky on loaded machines, so it runs only with GFI_TIMING_TEST=1.
+func TestDiffSnapshotsScaling(t *testing.T) {
+	if os.Getenv("GFI_TIMING_TEST") == "" {
+		t.Skip("set GFI_TIMING_TEST=1 to run timing test")
+	}
+
+	// perRow returns the fastest time per row of some runs.
+	perRow := func(n int) time.Duration {
+		snapshots := benchmarkSnapshots(n)
+		var min time.Duration
+		for i := 0; i < 3; i++ {
+			start := time.Now()
+			diffSnapshots(snapshots, func(FileInfo) bool { return true }, func(FileInfo) bool { return true }, func([]string) {})
+			if d := time.Since(start); i == 0 || d < min {
+				min = d
+			}
+		}
+		return min / time.Duration(n)
+	}
+
+	small, large := perRow(10000), perRow(100000)
+	t.Logf("10000: %v/row, 100000: %v/row", small, large)
+	if large > small*3 {
+		t.Errorf("Expect: near linear scaling Actual: %v/row at 10000 rows, %v/row at 100000 rows", small, large)
+	}
+}
+
+// TestReadFileInfos is test readFileInfos with csv of other layouts.
+func TestReadFileInfos(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+
+	write := func(name, data string) string {
+		p := filepath.Join(tmp, name)
+		err := ioutil.WriteFile(p, []byte(data), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	// Fixed 8 columns of version 1 without format marker.
+	fis, err := readFileInfos(write("v1.csv", "Full,Rel,Abs,Name,Time,Size,Mode,Type\r\n/a/b,a/b,/a/b,b,2017/03/25 12:00:00.000,1,-rw-r--r--,file\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 || fis[0].Rel != "a/b" || fis[0].Size != "1" || fis[0].Type != FILE {
+		t.Fatalf("Expect: [a/b 1 file] Actual: [%v]", fis)
+	}
+
+	// Reordered, extra and missing columns.
+	fis, err = readFileInfos(write("v2.csv", "#gfi-format 2\r\nSize,Extra,Rel\r\n2,x,c/d\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fis) != 1 || fis[0].Rel != "c/d" || fis[0].Size != "2" || fis[0].Name != "d" || fis[0].Full != "c/d" {
+		t.Fatalf("Expect: [c/d 2 d] Actual: [%v]", fis)
+	}
+
+	// Not gfi csv.
+	for name, data := range map[string]string{
+		"other.csv":     "Key,Value\r\na,1\r\n",
+		"reordered.csv": "Size,Extra,Rel\r\n2,x,c/d\r\n",
+		"norel.csv":     "#gfi-format 2\r\nKey,Value\r\na,1\r\n",
+	} {
+		_, err = readFileInfos(write(name, data))
+		if err == nil || !strings.Contains(err.Error(), "Not a csv of gfi get") {
+			t.Fatalf("%s: Expect: error of not gfi csv Actual: [%v]", name, err)
+		}
+	}
+
+	// Unknown format versions.
+	for name, data := range map[string]string{
+		"v9.csv": "#gfi-format 9\r\nSize,Rel\r\n2,c/d\r\n",
+		"vx.csv": "#gfi-format x\r\nSize,Rel\r\n2,c/d\r\n",
+		"v0.csv": "#gfi-format 0\r\nSize,Rel\r\n2,c/d\r\n",
+	} {
+		_, err = readFileInfos(write(name, data))
+		if err == nil || !strings.Contains(err.Error(), "format version") {
+			t.Fatalf("%s: Expect: error of format version Actual: [%v]", name, err)
+		}
+	}
+}
+
+// TestDiffCmdRunVersion1 is test diffCmd.Run with csv of version 1 and current version.
+func TestDiffCmdRunVersion1(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	outDir, err := ioutil.TempDir("", "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(outDir)
+	defer func() { columns, hashes = nil, nil }()
+
+	// Version 1 is the fixed 8 columns without format marker.
+	c1 := filepath.Join(outDir, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-c", "Full,Rel,Abs,Name,Time,Size,Mode,Type", "-o", c1, tmp})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker := fmt.Sprint(formatMarker, FormatVersion, "\r\n")
+	if !strings.HasPrefix(string(b), marker) {
+		t.Fatalf("Expect: [%v] Actual: [%v]", marker, string(b[:20]))
+	}
+	err = ioutil.WriteFile(c1, b[len(marker):], os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Current version with hash columns.
+	err = ioutil.WriteFile(filepath.Join(tmp, "file0"), []byte("changed"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns = nil
+	c2 := filepath.Join(outDir, getCsv2)
+	RootCmd.SetArgs([]string{"get", "-H", "md5", "-o", c2, tmp})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := filepath.Join(outDir, diffCsv1)
+	RootCmd.SetArgs([]string{"diff", "-o", d, c1, c2})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs := make(map[string]bool)
+	for _, r := range records[1:] {
+		if filepath.Base(r[0]) == "file0" {
+			diffs[r[2]] = true
+		} else if r[0] != tmp || r[2] != FileTime.String() {
+			t.Fatalf("Expect: diff of [file0] Actual: [%v]", r)
+		}
+	}
+	if !diffs[FileSize.String()] || diffs[FileFull.String()] {
+		t.Fatalf("Expect: [%v] Actual: [%v]", FileSize.String(), diffs)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Cmd options.
+	minSize       string
+	maxSize       string
+	newerThan     string
+	olderThan     string
+	newerThanFile string
+	byDiskUsage   bool
+
+	// Parsed range filters. Sizes are -1 and times are zero if not given.
+	filterMinSize int64 = -1
+	filterMaxSize int64 = -1
+	filterNewer   time.Time
+	filterOlder   time.Time
+)
+
+// sizeUnits is multiplier of size unit.
+var sizeUnits = map[string]float64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+	"P": 1 << 50,
+}
+
+// initFilter parses size and time range filters.
+func initFilter() error {
+	var err error
+
+	filterMinSize, filterMaxSize = -1, -1
+	filterNewer, filterOlder = time.Time{}, time.Time{}
+
+	if minSize != "" {
+		filterMinSize, err = parseSize(minSize)
+		if err != nil {
+			return err
+		}
+	}
+	if maxSize != "" {
+		filterMaxSize, err = parseSize(maxSize)
+		if err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	if newerThan != "" {
+		filterNewer, err = parseTimeArg(newerThan, now)
+		if err != nil {
+			return err
+		}
+	}
+	if olderThan != "" {
+		filterOlder, err = parseTimeArg(olderThan, now)
+		if err != nil {
+			return err
+		}
+	}
+	// The later one is used with --newer-than.
+	if newerThanFile != "" {
+		f, err := os.Stat(newerThanFile)
+		if err != nil {
+			return err
+		}
+		if f.ModTime().After(filterNewer) {
+			filterNewer = f.ModTime()
+		}
+	}
+	return nil
+}
+
+// parseSize parses size with optional binary unit. (ex: 1024, 10K, 1.5M, 2GB, 1TiB)
+func parseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	unit := strings.TrimLeft(s, ".0123456789")
+	mul, ok := sizeUnits[unit]
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s, unit), 64)
+	if !ok || err != nil || n < 0 {
+		return 0, fmt.Errorf("Unknown size. [%s]", value)
+	}
+	return int64(n * mul), nil
+}
+
+// parseTimeArg parses duration before now, date, or time in any of supported time formats.
+// Durations accept d (day) and w (week) in addition to Go duration units. (ex: 36h, 7d, 2w)
+// Dates are parsed before times, so 20171001 is a date, not unix epoch.
+func parseTimeArg(value string, now time.Time) (time.Time, error) {
+	if d, err := parseAge(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "20060102", "2006-01-02 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, timeLoc); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := parseTime(value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("Unknown duration or time. [%s]", value)
+}
+
+// parseAge parses duration with d (day) and w (week) units.
+func parseAge(value string) (time.Duration, error) {
+	for unit, d := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(value, unit), 64); err == nil && strings.HasSuffix(value, unit) {
+			return time.Duration(n * float64(d)), nil
+		}
+	}
+	return time.ParseDuration(value)
+}
+
+// passFilter returns whether size (or disk usage) and modified time are in filter ranges.
+func passFilter(size, usage string, mtime time.Time) bool {
+	return passSize(size, usage) && passTime(mtime)
+}
+
+// passEntry returns whether file of typ is in filter ranges.
+// Size ranges apply to files only, since size of directory entry is not of its contents.
+func passEntry(typ, size, usage string, mtime time.Time) bool {
+	return (typ == DIR || passSize(size, usage)) && passTime(mtime)
+}
+
+// passSize returns whether size (or disk usage) is in size range.
+func passSize(size, usage string) bool {
+	if filterMinSize < 0 && filterMaxSize < 0 {
+		return true
+	}
+	v := size
+	if byDiskUsage {
+		v = usage
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return false
+	}
+	return (filterMinSize < 0 || n >= filterMinSize) && (filterMaxSize < 0 || n <= filterMaxSize)
+}
+
+// passTime returns whether modified time is in time range.
+func passTime(mtime time.Time) bool {
+	if !filterNewer.IsZero() && !mtime.After(filterNewer) {
+		return false
+	}
+	if !filterOlder.IsZero() && !mtime.Before(filterOlder) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestParseSize is test parseSize of each unit.
+func TestParseSize(t *testing.T) {
+	values := map[string]int64{
+		"0":     0,
+		"100":   100,
+		"10K":   10 << 10,
+		"10kb":  10 << 10,
+		"1.5M":  3 << 19,
+		"2GiB":  2 << 30,
+		" 1T ":  1 << 40,
+		"1024B": 1024,
+	}
+	for v, e := range values {
+		n, err := parseSize(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != e {
+			t.Errorf("[%s]: Expected [%d] but actual: [%d]\n", v, e, n)
+		}
+	}
+	for _, v := range []string{"", "M", "-1", "10X", "1.2.3"} {
+		if _, err := parseSize(v); err == nil {
+			t.Errorf("[%s]: Expected error but nil\n", v)
+		}
+	}
+}
+
+// TestParseTimeArg is test parseTimeArg of durations and times.
+func TestParseTimeArg(t *testing.T) {
+	now := time.Date(2017, 3, 25, 12, 0, 0, 0, time.Local)
+	values := map[string]time.Time{
+		"36h":                     now.Add(-36 * time.Hour),
+		"7d":                      now.AddDate(0, 0, -7),
+		"2w":                      now.AddDate(0, 0, -14),
+		"2017-03-01":              time.Date(2017, 3, 1, 0, 0, 0, 0, time.Local),
+		"20171001":                time.Date(2017, 10, 1, 0, 0, 0, 0, time.Local),
+		"2017/03/01 10:00:00.000": time.Date(2017, 3, 1, 10, 0, 0, 0, time.Local),
+		"2017-03-01T00:00:00Z":    time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for v, e := range values {
+		tm, err := parseTimeArg(v, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tm.Equal(e) {
+			t.Errorf("[%s]: Expected [%s] but actual: [%s]\n", v, e, tm)
+		}
+	}
+	if _, err := parseTimeArg("yesterday", now); err == nil {
+		t.Error("Expected error but nil")
+	}
+}
+
+// TestGetCmdRunFilter is test getCmd.Run with size and time range filters.
+func TestGetCmdRunFilter(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() {
+		columns, fileOnly, minSize, maxSize, newerThan, olderThan, newerThanFile = nil, false, "", "", "", "", ""
+	}()
+
+	// file0 (10, old), file1 (100, old), file2 (1000, new), filedir and stamp between them.
+	err = os.Mkdir(filepath.Join(tmp, "filedir"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-48 * time.Hour)
+	for i, size := range []int{10, 100, 1000} {
+		p := filepath.Join(tmp, fmt.Sprintf("file%d", i))
+		err = ioutil.WriteFile(p, make([]byte, size), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 2 {
+			err = os.Chtimes(p, old, old)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	stamp := filepath.Join(tmp, "stamp")
+	err = ioutil.WriteFile(stamp, nil, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(stamp, old.Add(time.Hour), old.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"-f", "--min-size", "50"}, []string{"file1", "file2"}},
+		{[]string{"-f", "--min-size", "50", "--max-size", "0.5K"}, []string{"file1"}},
+		{[]string{"-f", "--newer-than", "1d"}, []string{"file2"}},
+		{[]string{"-f", "--older-than", "1d", "--min-size", "1"}, []string{"file0", "file1"}},
+		{[]string{"-f", "--newer-than-file", stamp}, []string{"file2"}},
+		// Size range does not apply to directories.
+		{[]string{"--min-size", "5K"}, []string{"filedir"}},
+	}
+	c := filepath.Join(tmp, getCsv1)
+	for _, tc := range cases {
+		RootCmd.SetArgs(append(append([]string{"get", "-c", "Name", "-o", c}, tc.args...), filepath.Join(tmp, "file*")))
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := newGetReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0)
+		for _, r := range records[1:] {
+			names = append(names, r[0])
+		}
+		sort.Strings(names)
+		if fmt.Sprint(names) != fmt.Sprint(tc.expected) {
+			t.Errorf("%v: Expected %v but actual: %v\n", tc.args, tc.expected, names)
+		}
+		fileOnly, minSize, maxSize, newerThan, olderThan, newerThanFile = false, "", "", "", "", ""
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// ValueKind is type of output value.
+type ValueKind int
+
+const (
+	// StringKind is string value.
+	StringKind ValueKind = iota
+	// NumberKind is numeric value.
+	NumberKind
+	// TimeKind is time value.
+	TimeKind
+)
+
+// KindFunc returns value kind of record's i-th column.
+type KindFunc func(record []string, i int) ValueKind
+
+// RecordWriter writes records to output.
+type RecordWriter interface {
+	// Write writes one record.
+	Write(record []string) error
+	// Close flushes buffered records and writes footer if any.
+	Close() error
+}
+
+// RecordWriterFactory returns RecordWriter which writes header and records to w.
+type RecordWriterFactory func(w io.Writer, header []string, kinds KindFunc) (RecordWriter, error)
+
+type recordFormat struct {
+	ext     string
+	factory RecordWriterFactory
+}
+
+// recordFormats is registered output formats by name.
+var recordFormats = map[string]recordFormat{
+	CSV:      {ext: "csv", factory: newDelimitedWriter(',')},
+	TSV:      {ext: "tsv", factory: newDelimitedWriter('\t')},
+	JSON:     {ext: "json", factory: newJSONWriter(false)},
+	JSONL:    {ext: "jsonl", factory: newJSONWriter(true)},
+	MARKDOWN: {ext: "md", factory: newMarkdownWriter},
+}
+
+// RegisterFormat registers output format name with output file extension.
+func RegisterFormat(name, ext string, factory RecordWriterFactory) {
+	recordFormats[name] = recordFormat{ext: ext, factory: factory}
+}
+
+// formatNames returns sorted registered format names.
+func formatNames() []string {
+	names := make([]string, 0, len(recordFormats))
+	for name := range recordFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateFormat checks output format name.
+func validateFormat(format string) error {
+	if _, ok := recordFormats[format]; ok {
+		return nil
+	}
+	return fmt.Errorf("Unknown format. [%s] (%s)", format, strings.Join(formatNames(), ", "))
+}
+
+// output writes records to out path in output format.
+// Records are written to stdout if out path is STDOUT.
+// The file is removed on close if no records are written.
+type output struct {
+	path   string
+	header []string
+	kinds  KindFunc
+	file   *os.File
+	writer RecordWriter
+	rows   int
+	// marker is line written before header if not empty.
+	marker string
+}
+
+func newOutput(path string, header []string, kinds KindFunc) *output {
+	return &output{path: path, header: header, kinds: kinds}
+}
+
+// Open creates output file and writes header.
+func (o *output) Open() error {
+	var (
+		err error
+		w   io.Writer = os.Stdout
+	)
+
+	if o.path != STDOUT {
+		os.MkdirAll(filepath.Dir(o.path), os.ModePerm)
+		o.file, err = os.Create(o.path)
+		if err != nil {
+			return err
+		}
+		w = o.file
+	}
+	if sjisOut {
+		w = transform.NewWriter(w, japanese.ShiftJIS.NewEncoder())
+	}
+	if o.marker != "" {
+		_, err = fmt.Fprint(w, o.marker+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	o.writer, err = recordFormats[format].factory(w, o.header, o.kinds)
+	return err
+}
+
+// Write writes record. Output is opened if not yet.
+func (o *output) Write(record []string) error {
+	if o.writer == nil {
+		err := o.Open()
+		if err != nil {
+			return err
+		}
+	}
+	o.rows++
+	return o.writer.Write(record)
+}
+
+// Close flushes records and closes output file.
+func (o *output) Close() error {
+	if o.writer == nil {
+		return nil
+	}
+	err := o.writer.Close()
+	if o.file == nil {
+		return err
+	}
+	if cerr := o.file.Close(); err == nil {
+		err = cerr
+	}
+	if o.rows == 0 {
+		os.RemoveAll(o.path)
+	}
+	return err
+}
+
+type delimitedWriter struct {
+	w *csv.Writer
+}
+
+// newDelimitedWriter returns factory of csv like writer with CRLF.
+func newDelimitedWriter(comma rune) RecordWriterFactory {
+	return func(w io.Writer, header []string, kinds KindFunc) (RecordWriter, error) {
+		writer := csv.NewWriter(w)
+		writer.Comma = comma
+		writer.UseCRLF = true
+		err := writer.Write(header)
+		if err != nil {
+			return nil, err
+		}
+		return &delimitedWriter{w: writer}, nil
+	}
+}
+
+// Write writes record.
+func (d *delimitedWriter) Write(record []string) error {
+	return d.w.Write(record)
+}
+
+// Close flushes writer.
+func (d *delimitedWriter) Close() error {
+	d.w.Flush()
+	return d.w.Error()
+}
+
+type jsonWriter struct {
+	w      io.Writer
+	header []string
+	kinds  KindFunc
+	lines  bool
+	rows   int
+}
+
+// newJSONWriter returns factory of json array writer, or json lines writer if lines.
+func newJSONWriter(lines bool) RecordWriterFactory {
+	return func(w io.Writer, header []string, kinds KindFunc) (RecordWriter, error) {
+		return &jsonWriter{w: w, header: header, kinds: kinds, lines: lines}, nil
+	}
+}
+
+// Write writes record as json object keyed by header.
+func (j *jsonWriter) Write(record []string) error {
+	b, err := marshalRecord(j.header, record, j.kinds)
+	if err != nil {
+		return err
+	}
+	prefix := ""
+	if !j.lines {
+		prefix = ",\n"
+		if j.rows == 0 {
+			prefix = "[\n"
+		}
+	}
+	j.rows++
+	_, err = fmt.Fprintf(j.w, "%s%s", prefix, b)
+	if err == nil && j.lines {
+		_, err = fmt.Fprintln(j.w)
+	}
+	return err
+}
+
+// Close closes json array.
+func (j *jsonWriter) Close() error {
+	if j.lines {
+		return nil
+	}
+	var err error
+	if j.rows == 0 {
+		_, err = fmt.Fprintln(j.w, "[]")
+	} else {
+		_, err = fmt.Fprintln(j.w, "\n]")
+	}
+	return err
+}
+
+type markdownWriter struct {
+	w io.Writer
+}
+
+// newMarkdownWriter returns markdown table writer.
+func newMarkdownWriter(w io.Writer, header []string, kinds KindFunc) (RecordWriter, error) {
+	m := &markdownWriter{w: w}
+	err := m.Write(header)
+	if err != nil {
+		return nil, err
+	}
+	separator := make([]string, len(header))
+	for i := range separator {
+		separator[i] = "---"
+	}
+	return m, m.Write(separator)
+}
+
+// Write writes record as markdown table row.
+func (m *markdownWriter) Write(record []string) error {
+	cells := make([]string, len(record))
+	for i, v := range record {
+		cells[i] = strings.Replace(v, "|", "\\|", -1)
+	}
+	_, err := fmt.Fprintf(m.w, "| %s |\n", strings.Join(cells, " | "))
+	return err
+}
+
+// Close does nothing.
+func (m *markdownWriter) Close() error {
+	return nil
+}
+
+// marshalRecord returns json object of record with header order.
+func marshalRecord(header, record []string, kinds KindFunc) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, name := range header {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		var v interface{}
+		if i < len(record) {
+			v = typedValue(record[i], kinds(record, i))
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// typedValue converts value to kind. Empty value is null and unparsable value is left as string.
+func typedValue(value string, kind ValueKind) interface{} {
+	if value == "" {
+		return nil
+	}
+	switch kind {
+	case NumberKind:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case TimeKind:
+		if t, err := parseTime(value); err == nil {
+			return t.Format(time.RFC3339Nano)
+		}
+	}
+	return value
+}
+
+// kindOf returns value kind of column name.
+func kindOf(name string) ValueKind {
+	switch name {
+	case FileSize.String(), FileUID.String(), FileGID.String(), FileDev.String(), FileInode.String(), FileNlink.String(), FileDiskUsage.String(), DirFileCount.String(), DirDirCount.String(), DirDepth.String():
+		return NumberKind
+	case FileTime.String(), FileATime.String(), FileCTime.String(), FileBTime.String():
+		return TimeKind
+	}
+	return StringKind
+}
+
+// headerKinds returns KindFunc by header column name.
+func headerKinds(header []string) KindFunc {
+	return func(record []string, i int) ValueKind {
+		return kindOf(header[i])
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestRecordWriters is test registered RecordWriter output.
+func TestRecordWriters(t *testing.T) {
+
+	header := []string{"Rel", "Size", "Time"}
+	record := []string{"a|b", "10", ""}
+	expects := map[string]string{
+		CSV:      "Rel,Size,Time\r\na|b,10,\r\n",
+		TSV:      "Rel\tSize\tTime\r\na|b\t10\t\r\n",
+		JSON:     "[\n{\"Rel\":\"a|b\",\"Size\":10,\"Time\":null}\n]\n",
+		JSONL:    "{\"Rel\":\"a|b\",\"Size\":10,\"Time\":null}\n",
+		MARKDOWN: "| Rel | Size | Time |\n| --- | --- | --- |\n| a\\|b | 10 |  |\n",
+	}
+
+	for name, es := range expects {
+		buf := new(bytes.Buffer)
+		w, err := recordFormats[name].factory(buf, header, headerKinds(header))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Write(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if as := buf.String(); as != es {
+			t.Fatalf("[%s] Expected: [%q] but actual: [%q]\n", name, es, as)
+		}
+	}
+
+	err := validateFormat("xml")
+	if err == nil {
+		t.Fatal("Expected error of unknown format")
+	}
+}
@@ -15,17 +15,14 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
-
 	"github.com/spf13/cobra"
 	"github.com/yukimemi/core"
 	"github.com/yukimemi/file"
@@ -34,6 +31,13 @@ import (
 var (
 	// Cmd options.
 	sortFlg bool
+	hashes  []string
+	columns []string
+
+	followSymlinks bool
+
+	// fileColumns is output columns of get.
+	fileColumns []FileInfoValue
 )
 
 // getCmd represents the get command
@@ -56,6 +60,14 @@ func init() {
 	getCmd.Flags().BoolVarP(&sortFlg, "sort", "s", false, "Sort flag")
 	// Skip flag.
 	getCmd.Flags().BoolVarP(&errSkip, "err", "e", false, "Skip getting file information on error")
+	// Hash algorithms.
+	getCmd.Flags().StringSliceVarP(&hashes, "hash", "H", nil, "Hash algorithms of file contents with comma separated (md5, sha1, sha256, xxhash)")
+	// Follow symbolic links.
+	getCmd.Flags().BoolVarP(&followSymlinks, "follow-symlinks", "L", false, "Follow symbolic links (loops are skipped)")
+	// Glob filters.
+	addGlobFlags(getCmd)
+	// Output columns.
+	getCmd.Flags().StringSliceVarP(&columns, "columns", "c", nil, "Output column names with comma separated ("+strings.Join(fileColumnNames(), ", ")+")")
 }
 
 func executeGet(cmd *cobra.Command, args []string) {
@@ -68,6 +80,8 @@ func executeGet(cmd *cobra.Command, args []string) {
 		wg  = new(sync.WaitGroup)
 	)
 
+	cnt = 0
+
 	if len(args) == 0 {
 		cmd.Help()
 		return
@@ -78,11 +92,22 @@ func executeGet(cmd *cobra.Command, args []string) {
 		log.Fatalln(err)
 	}
 
+	// Check hash algorithms and columns. Only output hashes are computed.
+	err = validateHashes(hashes)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fileColumns, err = getFileColumns(columns, hashes)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	hashes = columnHashes(fileColumns)
+
 	for _, root := range args {
 		wg.Add(1)
 		go func(root string) {
 			defer wg.Done()
-			err = getFileInfo(root, fi)
+			err := getFileInfo(root, fi)
 			if err != nil {
 				log.Fatalln(err)
 			}
@@ -95,30 +120,20 @@ func executeGet(cmd *cobra.Command, args []string) {
 		close(fi)
 	}()
 
-	// Create output csv file.
-	os.MkdirAll(filepath.Dir(out), os.ModePerm)
-	c, err := os.Create(out)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer c.Close()
-	var writer *csv.Writer
-	if sjisOut {
-		writer = csv.NewWriter(transform.NewWriter(c, japanese.ShiftJIS.NewEncoder()))
-	} else {
-		writer = csv.NewWriter(c)
+	// Output header and records.
+	header := getFileCsvHeader()
+	writer := newOutput(out, header, headerKinds(header))
+	// Csv carries format version for diff.
+	if format == CSV {
+		writer.marker = fmt.Sprint(formatMarker, FormatVersion)
 	}
-	writer.Comma = ','
-	writer.UseCRLF = true
-
-	// Write header.
-	err = writer.Write(getFileCsvHeader())
+	err = writer.Open()
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	// Receive and output.
-	for f := range fi {
+	for f := range hashFileInfos(fi, hashes) {
 		cnt++
 		if !silent {
 			fmt.Fprintf(os.Stderr, "Count: %d\r", cnt)
@@ -143,13 +158,14 @@ func executeGet(cmd *cobra.Command, args []string) {
 			}
 		}
 	}
-	writer.Flush()
+	err = writer.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
 	if cnt == 0 {
-		fmt.Println("There is no information to get.")
-		c.Close()
-		os.RemoveAll(out)
+		fmt.Fprintln(os.Stderr, "There is no information to get.")
 	} else {
-		fmt.Printf("Write to [%s]. ([%d] row)\n", out, cnt)
+		fmt.Fprintf(os.Stderr, "Write to [%s]. ([%d] row)\n", outName(), cnt)
 	}
 }
 
@@ -166,7 +182,25 @@ func getFileInfo(root string, fi chan FileInfo) error {
 		}
 	)
 
-	if fileOnly && !dirOnly {
+	g, err := newGlobFilter(root)
+	if err != nil {
+		return err
+	}
+
+	if followSymlinks {
+		rp, err := realPath(root)
+		if err != nil {
+			return err
+		}
+		return followFileInfo(root, root, []string{rp}, g, fi)
+	}
+
+	// Glob filter needs directories to prune and read ignore files.
+	if g != nil {
+		infos, err = walkInfos(root, opt, func(path string, f os.FileInfo) bool {
+			return g.skip(path, path, f)
+		})
+	} else if fileOnly && !dirOnly {
 		infos, err = file.GetFiles(root, opt)
 	} else if !fileOnly && dirOnly {
 		infos, err = file.GetDirs(root, opt)
@@ -186,7 +220,10 @@ func getFileInfo(root string, fi chan FileInfo) error {
 			}
 			return f.Err
 		}
-		abs, err := filepath.Abs(f.Path)
+		if g != nil && !wantType(f.Fi) {
+			continue
+		}
+		info, err := newFileInfo(f.Path, f.Path, f.Fi, f.Fi)
 		if err != nil {
 			if errSkip {
 				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
@@ -194,29 +231,203 @@ func getFileInfo(root string, fi chan FileInfo) error {
 			}
 			return err
 		}
-		full, err := filepath.Abs(file.ShareToAbs(f.Path))
-		if err != nil {
+		if !passEntry(info.Type, info.Size, info.DiskUsage, f.Fi.ModTime()) {
+			continue
+		}
+		fi <- info
+	}
+	return err
+}
+
+// followFileInfo gets file information of dir following symbolic links.
+// Paths under dir are output under display path.
+// chain is real paths of walking directories to detect link loops.
+func followFileInfo(dir, display string, chain []string, g *globFilter, fi chan FileInfo) error {
+
+	var (
+		err   error
+		infos chan file.Info
+
+		opt = file.Option{
+			Matches: matches,
+			Ignores: ignores,
+			Recurse: true,
+		}
+	)
+
+	// Links are checked by glob filter with the followed stat.
+	if g != nil {
+		infos, err = walkInfos(dir, opt, func(path string, f os.FileInfo) bool {
+			if path == dir && dir != display {
+				return false
+			}
+			if f.Mode()&os.ModeSymlink != 0 {
+				if s, err := os.Stat(path); err == nil {
+					f = s
+				}
+			}
+			return g.skip(display+strings.TrimPrefix(path, dir), path, f)
+		})
+	} else {
+		infos, err = file.GetInfos(dir, opt)
+	}
+	if err != nil {
+		return err
+	}
+
+	for f := range infos {
+		if f.Err != nil {
 			if errSkip {
-				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", f.Err)
 				continue
 			}
-			return err
+			return f.Err
 		}
-		fi <- FileInfo{
-			Full: full,
-			Abs:  abs,
-			Rel:  f.Path,
-			Name: f.Fi.Name(),
-			Time: f.Fi.ModTime().Format("2006/01/02 15:04:05.000"),
-			Size: fmt.Sprint(f.Fi.Size()),
-			Mode: f.Fi.Mode().String(),
-			Type: getType(f.Fi),
+		// The linked directory itself is output as the link.
+		if f.Path == dir && dir != display {
+			continue
+		}
+		path := display + strings.TrimPrefix(f.Path, dir)
+
+		// Follow the link. Broken link is output as it is.
+		var target string
+		stat := f.Fi
+		if f.Fi.Mode()&os.ModeSymlink != 0 {
+			if s, err := os.Stat(f.Path); err == nil {
+				stat = s
+				if s.IsDir() {
+					target, err = realPath(f.Path)
+					// Root link is followed always.
+					if err == nil && f.Path != dir && linkLoop(f.Path, target, chain) {
+						fmt.Fprintf(os.Stderr, "Warning: Symlink loop [%s] -> [%s]. skip.\n", path, target)
+						target = ""
+					}
+					if err != nil {
+						if errSkip {
+							fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+							continue
+						}
+						return err
+					}
+				}
+			}
+		}
+
+		if wantType(stat) {
+			info, err := newFileInfo(path, f.Path, f.Fi, stat)
+			if err != nil {
+				if errSkip {
+					fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+					continue
+				}
+				return err
+			}
+			if passEntry(info.Type, info.Size, info.DiskUsage, stat.ModTime()) {
+				fi <- info
+			}
+		}
+
+		if target != "" {
+			err = followFileInfo(target, path, append(chain, target), g, fi)
+			if err != nil {
+				return err
+			}
 		}
 	}
-	return err
+	return nil
+}
+
+// newFileInfo returns FileInfo of path.
+// lf is Lstat of real path rp and f is followed stat. (same as lf if not followed)
+func newFileInfo(path, rp string, lf, f os.FileInfo) (FileInfo, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	full, err := filepath.Abs(file.ShareToAbs(path))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	info := FileInfo{
+		Full: full,
+		Abs:  abs,
+		Rel:  path,
+		Name: lf.Name(),
+		Time: formatTime(f.ModTime()),
+		Size: fmt.Sprint(f.Size()),
+		Mode: f.Mode().String(),
+		Type: getType(f),
+	}
+	setOwner(&info, f)
+	setInode(&info, f)
+	setDiskUsage(&info, f)
+	setLink(&info, rp, lf)
+	// Times of followed link are of the target.
+	if info.LinkTarget != "" && f != lf {
+		rp, err = filepath.EvalSymlinks(rp)
+		if err != nil {
+			return FileInfo{}, err
+		}
+	}
+	setTimes(&info, rp, f, hasFileColumn(FileBTime))
+	return info, nil
+}
+
+// wantType returns whether f is output with file only and dir only flags.
+func wantType(f os.FileInfo) bool {
+	if fileOnly && !dirOnly {
+		return !f.IsDir()
+	}
+	if !fileOnly && dirOnly {
+		return f.IsDir()
+	}
+	return true
+}
+
+// setLink sets link target and broken of symbolic link f to fi.
+func setLink(fi *FileInfo, path string, f os.FileInfo) {
+	if f.Mode()&os.ModeSymlink == 0 {
+		return
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return
+	}
+	fi.LinkTarget = target
+	fi.Broken = "false"
+	if _, err := os.Stat(path); err != nil {
+		fi.Broken = "true"
+	}
+}
+
+// realPath returns absolute path of p with symbolic links evaluated.
+func realPath(p string) (string, error) {
+	p, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(p)
+}
+
+// linkLoop returns whether following link to target directory loops.
+// It loops if target is one of chain or an ancestor of them or of link itself.
+func linkLoop(link, target string, chain []string) bool {
+	parent, err := realPath(filepath.Dir(link))
+	if err != nil {
+		return true
+	}
+	for _, p := range append(chain, parent) {
+		if p == target || strings.HasPrefix(p, strings.TrimSuffix(target, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
 }
 
 func getType(f os.FileInfo) string {
+	if f.Mode()&os.ModeSymlink != 0 {
+		return SYMLINK
+	}
 	if f.IsDir() {
 		return DIR
 	}
@@ -224,23 +435,227 @@ func getType(f os.FileInfo) string {
 }
 
 func fileInfoToCsv(fi FileInfo) []string {
-	a := make([]string, FileMax)
-	a[FileFull-1] = fi.Full
-	a[FileRel-1] = fi.Rel
-	a[FileAbs-1] = fi.Abs
-	a[FileName-1] = fi.Name
-	a[FileTime-1] = fi.Time
-	a[FileSize-1] = fi.Size
-	a[FileMode-1] = fi.Mode
-	a[FileType-1] = fi.Type
+	a := make([]string, 0, len(fileColumns))
+	for _, fiv := range fileColumns {
+		a = append(a, fi.value(fiv))
+	}
 	return a
 }
 
 func getFileCsvHeader() []string {
-	var fiv FileInfoValue
 	header := make([]string, 0)
-	for fiv = 1; fiv <= FileMax; fiv++ {
+	for _, fiv := range fileColumns {
 		header = append(header, fiv.String())
 	}
 	return header
 }
+
+// getFileColumns returns output columns of column names.
+// Default columns and hash columns of algos are returned if names is empty.
+// "Hash" is hash columns of algos. (sha256 if no algos)
+// UID and GID are added with Owner and Group. Error if hash column of any of algos is not in names.
+func getFileColumns(names, algos []string) ([]FileInfoValue, error) {
+	var fiv FileInfoValue
+
+	hashCols := make([]FileInfoValue, 0)
+	for _, algo := range algos {
+		hashCols = append(hashCols, hashColumn(algo))
+	}
+
+	columns := make([]FileInfoValue, 0)
+	if len(names) == 0 {
+		for fiv = 1; fiv <= FileType; fiv++ {
+			columns = append(columns, fiv)
+		}
+		return append(columns, hashCols...), nil
+	}
+
+	for _, name := range names {
+		fiv = fileColumn(name)
+		switch {
+		case fiv == FileHash && len(hashCols) == 0:
+			columns = append(columns, FileSHA256)
+		case fiv == FileHash:
+			columns = append(columns, hashCols...)
+		case fiv == 0 || fiv > FileHash:
+			return nil, fmt.Errorf("Unknown column. [%s]", name)
+		default:
+			columns = append(columns, fiv)
+		}
+	}
+
+	// Ids are output with names to compare owners of hosts without the names.
+	for _, pair := range [][]FileInfoValue{{FileOwner, FileUID}, {FileGroup, FileGID}} {
+		if containsColumn(columns, pair[0]) && !containsColumn(columns, pair[1]) {
+			columns = append(columns, pair[1])
+		}
+	}
+
+	// Hash algorithms are computed only for output columns.
+	for i, algo := range algos {
+		if !containsColumn(columns, hashCols[i]) {
+			return nil, fmt.Errorf("Hash algorithm needs Hash column. [%s] (ex: -c Rel,Size,Hash)", algo)
+		}
+	}
+	return columns, nil
+}
+
+// containsColumn returns whether columns contains fiv.
+func containsColumn(columns []FileInfoValue, fiv FileInfoValue) bool {
+	for _, c := range columns {
+		if c == fiv {
+			return true
+		}
+	}
+	return false
+}
+
+// hasFileColumn returns whether fiv is output.
+func hasFileColumn(fiv FileInfoValue) bool {
+	return containsColumn(fileColumns, fiv)
+}
+
+// fileColumnNames returns selectable column names.
+func fileColumnNames() []string {
+	var fiv FileInfoValue
+	names := make([]string, 0)
+	for fiv = 1; fiv <= FileHash; fiv++ {
+		names = append(names, fiv.String())
+	}
+	return names
+}
+
+// fileColumn returns FileInfoValue of column name. (Case insensitive) 0 if unknown.
+func fileColumn(name string) FileInfoValue {
+	var fiv FileInfoValue
+	for fiv = 1; fiv <= FileMax; fiv++ {
+		if strings.EqualFold(fiv.String(), name) {
+			return fiv
+		}
+	}
+	return 0
+}
+
+// columnHashes returns hash algorithms of hash columns.
+func columnHashes(columns []FileInfoValue) []string {
+	algos := make([]string, 0)
+	for _, fiv := range columns {
+		for _, algo := range hashAlgos {
+			if hashColumn(algo) == fiv {
+				algos = append(algos, algo)
+			}
+		}
+	}
+	return algos
+}
+
+func (fi FileInfo) value(fiv FileInfoValue) string {
+	switch fiv {
+	case FileFull:
+		return fi.Full
+	case FileRel:
+		return fi.Rel
+	case FileAbs:
+		return fi.Abs
+	case FileName:
+		return fi.Name
+	case FileTime:
+		return fi.Time
+	case FileSize:
+		return fi.Size
+	case FileMode:
+		return fi.Mode
+	case FileType:
+		return fi.Type
+	case FileMD5:
+		return fi.MD5
+	case FileSHA1:
+		return fi.SHA1
+	case FileSHA256:
+		return fi.SHA256
+	case FileXXHash:
+		return fi.XXHash
+	case FileOwner:
+		return fi.Owner
+	case FileGroup:
+		return fi.Group
+	case FileUID:
+		return fi.UID
+	case FileGID:
+		return fi.GID
+	case FileATime:
+		return fi.ATime
+	case FileCTime:
+		return fi.CTime
+	case FileBTime:
+		return fi.BTime
+	case FileLinkTarget:
+		return fi.LinkTarget
+	case FileBroken:
+		return fi.Broken
+	case FileDev:
+		return fi.Dev
+	case FileInode:
+		return fi.Inode
+	case FileNlink:
+		return fi.Nlink
+	case FileDiskUsage:
+		return fi.DiskUsage
+	}
+	return ""
+}
+
+func (fi *FileInfo) setValue(fiv FileInfoValue, v string) {
+	switch fiv {
+	case FileFull:
+		fi.Full = v
+	case FileRel:
+		fi.Rel = v
+	case FileAbs:
+		fi.Abs = v
+	case FileName:
+		fi.Name = v
+	case FileTime:
+		fi.Time = v
+	case FileSize:
+		fi.Size = v
+	case FileMode:
+		fi.Mode = v
+	case FileType:
+		fi.Type = v
+	case FileMD5:
+		fi.MD5 = v
+	case FileSHA1:
+		fi.SHA1 = v
+	case FileSHA256:
+		fi.SHA256 = v
+	case FileXXHash:
+		fi.XXHash = v
+	case FileOwner:
+		fi.Owner = v
+	case FileGroup:
+		fi.Group = v
+	case FileUID:
+		fi.UID = v
+	case FileGID:
+		fi.GID = v
+	case FileATime:
+		fi.ATime = v
+	case FileCTime:
+		fi.CTime = v
+	case FileBTime:
+		fi.BTime = v
+	case FileLinkTarget:
+		fi.LinkTarget = v
+	case FileBroken:
+		fi.Broken = v
+	case FileDev:
+		fi.Dev = v
+	case FileInode:
+		fi.Inode = v
+	case FileNlink:
+		fi.Nlink = v
+	case FileDiskUsage:
+		fi.DiskUsage = v
+	}
+}
@@ -15,10 +15,15 @@
 package cmd
 
 import (
-	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 // TestGetCmdRun is test getCmd.Run.
@@ -46,10 +51,9 @@ func TestGetCmdRun(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	reader := csv.NewReader(f1)
+	reader := newGetReader(f1)
 	reader.Comma = ','
-	// Skip header.
-	_, err = reader.Read()
+	header, err := reader.Read()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,8 +64,9 @@ func TestGetCmdRun(t *testing.T) {
 
 	// Csv to FileInfos.
 	fis := make(FileInfos, 0)
+	cols := csvColumns(header)
 	for _, r := range left {
-		fis = append(fis, *csvToFileInfo(r))
+		fis = append(fis, *csvToFileInfo(cols, r))
 	}
 
 	// Check FileInfos.
@@ -136,3 +141,532 @@ func TestGetCmdRun(t *testing.T) {
 	}
 
 }
+
+// TestGetCmdRunHash is test getCmd.Run with hash flag.
+func TestGetCmdRunHash(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { hashes = nil }()
+
+	err = ioutil.WriteFile(filepath.Join(tmp, "file0"), []byte("gfi"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1 := filepath.Join(tmp, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-f", "-H", "md5,xxhash", "-o", c1, filepath.Join(tmp, "file0")})
+	err = RootCmd.Execute()
+	fileOnly = false
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check csv.
+	f1, err := os.Open(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+
+	reader := newGetReader(f1)
+	reader.Comma = ','
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected: [2] rows but actual: [%v]\n", len(records))
+	}
+
+	// Hash columns are appended after Type.
+	header, r := records[0], records[1]
+	md5Index, xxIndex := int(FileType), int(FileType)+1
+	if header[md5Index] != FileMD5.String() || header[xxIndex] != FileXXHash.String() {
+		t.Fatalf("Expected hash headers but actual: [%v]\n", header)
+	}
+	es := "9a3811dec624aaa1f69f52b85320c245"
+	if r[md5Index] != es {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", es, r[md5Index])
+	}
+	if len(r[xxIndex]) != 16 {
+		t.Fatalf("Expected xxhash but actual: [%v]\n", r[xxIndex])
+	}
+}
+
+// TestHashFileInfosOrder is test hashFileInfos outputs in received order.
+func TestHashFileInfosOrder(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func(n int) { hashWorkers = n }(hashWorkers)
+	hashWorkers = 8
+
+	// Larger files first to finish out of order.
+	n := 100
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		p := filepath.Join(tmp, fmt.Sprint("hash", i))
+		err := ioutil.WriteFile(p, make([]byte, (n-i)*10000), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	fi := make(chan FileInfo)
+	go func() {
+		for _, p := range paths {
+			fi <- FileInfo{Abs: p, Rel: p, Type: FILE}
+		}
+		close(fi)
+	}()
+
+	i := 0
+	for f := range hashFileInfos(fi, []string{SHA256}) {
+		if f.Rel != paths[i] {
+			t.Fatalf("Expected: [%v] but actual: [%v]\n", paths[i], f.Rel)
+		}
+		if f.SHA256 == "" {
+			t.Fatalf("Expected sha256 but actual: [%v]\n", f)
+		}
+		i++
+	}
+	if i != n {
+		t.Fatalf("Expected: [%v] rows but actual: [%v]\n", n, i)
+	}
+}
+
+// TestGetCmdRunJSONL is test getCmd.Run with jsonl format.
+func TestGetCmdRunJSONL(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { format, fileOnly = CSV, false }()
+
+	err = ioutil.WriteFile(filepath.Join(tmp, "file0"), []byte("gfi"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1 := filepath.Join(tmp, "one.jsonl")
+	RootCmd.SetArgs([]string{"get", "-f", "-F", "jsonl", "-o", c1, filepath.Join(tmp, "file0")})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fi struct {
+		Name string
+		Size int64
+		Time time.Time
+	}
+	err = json.Unmarshal(b, &fi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name != "file0" || fi.Size != 3 || fi.Time.IsZero() {
+		t.Fatalf("Expected: [file0 3 time] but actual: [%v]\n", fi)
+	}
+}
+
+// TestGetCmdRunStdout is test getCmd.Run output to stdout.
+func TestGetCmdRunStdout(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { fileOnly = false }()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	records := make(chan [][]string)
+	go func() {
+		rs, err := newGetReader(r).ReadAll()
+		if err != nil {
+			t.Error(err)
+		}
+		records <- rs
+	}()
+
+	RootCmd.SetArgs([]string{"get", "-f", "-o", "-", filepath.Join(tmp, "dir0")})
+	err = RootCmd.Execute()
+	w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := <-records
+	if len(rs) != 2 || rs[1][FileName-1] != "file0" {
+		t.Fatalf("Expected: [file0] but actual: [%v]\n", rs)
+	}
+	if _, err := os.Stat("-"); err == nil {
+		t.Fatal("Expected: no [-] file")
+	}
+}
+
+// TestGetCmdRunColumns is test getCmd.Run with columns flag.
+func TestGetCmdRunColumns(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, hashes, fileOnly = nil, nil, false }()
+
+	c1 := filepath.Join(tmp, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-f", "-c", "rel,Size,Hash", "-H", "md5", "-o", c1, filepath.Join(tmp, "file0")})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := os.Open(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	records, err := newGetReader(f1).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es := []string{FileRel.String(), FileSize.String(), FileMD5.String()}
+	if strings.Join(records[0], ",") != strings.Join(es, ",") {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", es, records[0])
+	}
+
+	// Read back by header name.
+	fi := csvToFileInfo(csvColumns(records[0]), records[1])
+	if fi.Rel != filepath.Join(tmp, "file0") || fi.Size != "0" || fi.MD5 != "d41d8cd98f00b204e9800998ecf8427e" {
+		t.Fatalf("Expected: [file0 0 md5] but actual: [%v]\n", fi)
+	}
+
+	// Unknown column.
+	_, err = getFileColumns([]string{"Rel", "Foo"}, nil)
+	if err == nil {
+		t.Fatal("Expected error of unknown column")
+	}
+
+	// Hash algorithm without Hash column.
+	_, err = getFileColumns([]string{"Rel", "Size"}, []string{MD5})
+	if err == nil {
+		t.Fatal("Expected error of hash algorithm without Hash column")
+	}
+	_, err = getFileColumns([]string{"Rel", "MD5"}, []string{MD5, SHA1})
+	if err == nil {
+		t.Fatal("Expected error of hash algorithm without Hash column")
+	}
+	_, err = getFileColumns([]string{"Rel", "MD5"}, []string{MD5})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGetCmdRunOwner is test getCmd.Run with owner columns.
+func TestGetCmdRunOwner(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Owner is not supported on windows")
+	}
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, fileOnly = nil, false }()
+
+	c1 := filepath.Join(tmp, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-f", "-c", "Rel,Owner,Group,UID,GID", "-o", c1, filepath.Join(tmp, "file0")})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := os.Open(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	records, err := newGetReader(f1).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi := csvToFileInfo(csvColumns(records[0]), records[1])
+	if fi.UID != fmt.Sprint(os.Getuid()) || fi.GID != fmt.Sprint(os.Getgid()) {
+		t.Fatalf("Expected: [%v %v] but actual: [%v %v]\n", os.Getuid(), os.Getgid(), fi.UID, fi.GID)
+	}
+	if fi.Owner == "" || fi.Group == "" {
+		t.Fatalf("Expected owner names but actual: [%v]\n", fi)
+	}
+
+	// Diff owner.
+	other := *fi
+	other.Owner, other.UID = "other", "12345"
+	diffs := compareFileInfo(*fi, other)
+	if len(diffs) != 1 || diffs[0].diff != FileOwner || diffs[0].value != fi.Owner {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", FileOwner, diffs)
+	}
+
+	// Names not found on the other host are compared by ids.
+	for _, o := range [][]string{{"", fi.UID}, {fi.UID, ""}, {fi.UID, fi.UID}} {
+		other = *fi
+		other.Owner, other.UID = o[0], o[1]
+		diffs = compareFileInfo(*fi, other)
+		if len(diffs) != 0 {
+			t.Fatalf("Expected: no difference of [%v] but actual: [%v]\n", o, diffs)
+		}
+	}
+	other.Owner, other.UID = "", "12345"
+	diffs = compareFileInfo(*fi, other)
+	if len(diffs) != 1 || diffs[0].diff != FileOwner {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", FileOwner, diffs)
+	}
+
+	// Ids are output with names.
+	cols, err := getFileColumns([]string{"Rel", "Owner", "Group"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(cols) != fmt.Sprint([]FileInfoValue{FileRel, FileOwner, FileGroup, FileUID, FileGID}) {
+		t.Fatalf("Expected: [Rel Owner Group UID GID] but actual: [%v]\n", cols)
+	}
+}
+
+// TestGetCmdRunTimes is test getCmd.Run with extended time columns.
+func TestGetCmdRunTimes(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, fileOnly = nil, false }()
+
+	c1 := filepath.Join(tmp, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-f", "-c", "Rel,Time,ATime,CTime,BTime", "-o", c1, filepath.Join(tmp, "file0")})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := os.Open(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	records, err := newGetReader(f1).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi := csvToFileInfo(csvColumns(records[0]), records[1])
+	if fi.ATime == "" {
+		t.Fatalf("Expected access time but actual: [%v]\n", fi)
+	}
+	// Birth time may be empty if the filesystem doesn't provide.
+	for _, v := range []string{fi.ATime, fi.CTime, fi.BTime} {
+		if v == "" {
+			continue
+		}
+		_, err = time.ParseInLocation(timeLayout, v, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestGetCmdRunSymlink is test getCmd.Run with symbolic links.
+func TestGetCmdRunSymlink(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, followSymlinks = nil, false }()
+
+	// links/file, links/link -> file, links/broken -> none, links/loop -> links, links/sub -> ../dir0
+	dir := filepath.Join(tmp, "links")
+	err = os.MkdirAll(filepath.Join(tmp, "dir0"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(tmp, "dir0", "sub"), []byte("sub"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "file"), []byte("file"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"link":   "file",
+		"broken": "none",
+		"loop":   ".",
+		"sub":    filepath.Join("..", "dir0"),
+	}
+	for name, target := range links {
+		err = os.Symlink(target, filepath.Join(dir, name))
+		if err != nil {
+			t.Skip(err)
+		}
+	}
+
+	get := func(args ...string) map[string]FileInfo {
+		c := filepath.Join(tmp, getCsv1)
+		RootCmd.SetArgs(append([]string{"get", "-c", "Rel,Type,Size,LinkTarget,Broken", "-o", c}, append(args, dir)...))
+		err := RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		records, err := newGetReader(f).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cols := csvColumns(records[0])
+		fis := make(map[string]FileInfo)
+		for _, r := range records[1:] {
+			fi := csvToFileInfo(cols, r)
+			rel, err := filepath.Rel(dir, fi.Rel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fis[filepath.ToSlash(rel)] = *fi
+		}
+		return fis
+	}
+
+	// Not followed.
+	fis := get()
+	for name, target := range links {
+		fi := fis[name]
+		if fi.Type != SYMLINK || fi.LinkTarget != target {
+			t.Errorf("Expected symlink to [%s] but actual: [%v]\n", target, fi)
+		}
+		if broken := fmt.Sprint(name == "broken"); fi.Broken != broken {
+			t.Errorf("Expected broken [%s] but actual: [%v]\n", broken, fi)
+		}
+	}
+	if fi := fis["file"]; fi.Type != FILE || fi.LinkTarget != "" || fi.Broken != "" {
+		t.Errorf("Expected file but actual: [%v]\n", fi)
+	}
+	if _, ok := fis["sub/sub"]; ok {
+		t.Errorf("Expected not followed but actual: [%v]\n", fis)
+	}
+
+	// Followed. Loop is skipped.
+	fis = get("-L")
+	if fi := fis["link"]; fi.Type != FILE || fi.Size != "4" || fi.LinkTarget != "file" {
+		t.Errorf("Expected followed file but actual: [%v]\n", fi)
+	}
+	if fi := fis["sub"]; fi.Type != DIR || fi.Broken != "false" {
+		t.Errorf("Expected followed directory but actual: [%v]\n", fi)
+	}
+	if fi := fis["sub/sub"]; fi.Type != FILE || fi.Size != "3" {
+		t.Errorf("Expected file in followed directory but actual: [%v]\n", fi)
+	}
+	if fi := fis["broken"]; fi.Type != SYMLINK || fi.Broken != "true" {
+		t.Errorf("Expected broken symlink but actual: [%v]\n", fi)
+	}
+	if fi := fis["loop"]; fi.Type != DIR {
+		t.Errorf("Expected loop directory but actual: [%v]\n", fi)
+	}
+	for rel := range fis {
+		if strings.HasPrefix(rel, "loop/") {
+			t.Errorf("Expected loop skipped but actual: [%s]\n", rel)
+		}
+	}
+}
+
+// TestGetCmdRunInode is test getCmd.Run with device and inode columns.
+func TestGetCmdRunInode(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Inode is not supported on windows")
+	}
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns = nil }()
+
+	err = os.Link(filepath.Join(tmp, "file0"), filepath.Join(tmp, "link0"))
+	if err != nil {
+		t.Skip(err)
+	}
+
+	c1 := filepath.Join(tmp, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-c", "Name,Dev,Inode,Nlink", "-o", c1, filepath.Join(tmp, "file0"), filepath.Join(tmp, "link0")})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f1, err := os.Open(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	records, err := newGetReader(f1).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 2 rows but actual: [%v]\n", records)
+	}
+
+	cols := csvColumns(records[0])
+	one, other := csvToFileInfo(cols, records[1]), csvToFileInfo(cols, records[2])
+	if one.Inode == "" || one.Dev != other.Dev || one.Inode != other.Inode || one.Nlink != "2" {
+		t.Errorf("Expected same inode with 2 links but actual: [%v] [%v]\n", one, other)
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/yukimemi/core"
+	"github.com/yukimemi/file"
+)
+
+var (
+	// Cmd options.
+	includes         []string
+	excludes         []string
+	excludeFrom      string
+	respectGitignore bool
+
+	// ignoreFiles are ignore files read with --respect-gitignore.
+	ignoreFiles = []string{".gitignore", ".ignore"}
+)
+
+// globRule is a compiled glob pattern. (.gitignore syntax)
+type globRule struct {
+	re      *regexp.Regexp
+	base    string
+	negate  bool
+	dirOnly bool
+}
+
+// globFilter filters walking paths of a root by glob rules.
+// It is shared by walks of followed links, so rules are guarded by mu.
+type globFilter struct {
+	mu       sync.Mutex
+	root     string
+	includes []globRule
+	excludes []globRule
+}
+
+// addGlobFlags adds glob filter flags to cmd.
+func addGlobFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&includes, "include", nil, "Include file glob (ex: *.go, src/**/*.js)")
+	cmd.Flags().StringArrayVar(&excludes, "exclude", nil, "Exclude glob (ex: *.log, node_modules/, /build)")
+	cmd.Flags().StringVar(&excludeFrom, "exclude-from", "", "Read exclude globs from file (.gitignore syntax)")
+	cmd.Flags().BoolVar(&respectGitignore, "respect-gitignore", false, "Exclude by .gitignore and .ignore files found during walk")
+}
+
+// newGlobFilter returns globFilter of root. nil if no glob options.
+func newGlobFilter(root string) (*globFilter, error) {
+	if len(includes) == 0 && len(excludes) == 0 && excludeFrom == "" && !respectGitignore {
+		return nil, nil
+	}
+	g := &globFilter{
+		root: filepath.Clean(root),
+	}
+	for _, p := range includes {
+		r, ok, err := newGlobRule(p, g.root)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			g.includes = append(g.includes, r)
+		}
+	}
+	for _, p := range excludes {
+		r, ok, err := newGlobRule(p, g.root)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			g.excludes = append(g.excludes, r)
+		}
+	}
+	if excludeFrom != "" {
+		rules, err := readGlobRules(excludeFrom, g.root)
+		if err != nil {
+			return nil, err
+		}
+		g.excludes = append(g.excludes, rules...)
+	}
+	return g, nil
+}
+
+// skip returns whether path is skipped. Skipped directories must not be walked into. (see walkInfos)
+// Includes select files only. dir is path to read ignore files if f is directory.
+func (g *globFilter) skip(path, dir string, f os.FileInfo) bool {
+	if g == nil {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	path = filepath.Clean(path)
+
+	if path != g.root {
+		if respectGitignore && f.IsDir() && f.Name() == ".git" || matchRules(g.excludes, path, f.IsDir()) {
+			return true
+		}
+		if len(g.includes) != 0 && !f.IsDir() && !matchRules(g.includes, path, false) {
+			return true
+		}
+	}
+
+	// Rules of ignore files apply under the directory.
+	if respectGitignore && f.IsDir() {
+		for _, name := range ignoreFiles {
+			rules, err := readGlobRules(filepath.Join(dir, name), path)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+				}
+				continue
+			}
+			g.excludes = append(g.excludes, rules...)
+		}
+	}
+	return false
+}
+
+// walkInfos walks root like file.GetInfos with Matches and Ignores of opt.
+// Paths skipped by skip are not output, and skipped directories are not walked into.
+func walkInfos(root string, opt file.Option, skip func(path string, f os.FileInfo) bool) (chan file.Info, error) {
+
+	var (
+		err    error
+		match  *regexp.Regexp
+		ignore *regexp.Regexp
+	)
+
+	if _, err = os.Lstat(root); err != nil {
+		return nil, err
+	}
+	if len(opt.Matches) != 0 {
+		match, err = core.CompileStrs(opt.Matches)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(opt.Ignores) != 0 {
+		ignore, err = core.CompileStrs(opt.Ignores)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	infos := make(chan file.Info)
+	go func() {
+		defer close(infos)
+		filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+			if err != nil {
+				infos <- file.Info{Path: path, Err: err}
+				return nil
+			}
+			if ignore != nil && ignore.MatchString(path) || skip(path, f) {
+				if f.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if match == nil || match.MatchString(path) {
+				infos <- file.Info{Path: path, Fi: f}
+			}
+			return nil
+		})
+	}()
+	return infos, nil
+}
+
+// matchRules returns whether the last matched rule is not negated.
+func matchRules(rules []globRule, path string, isDir bool) bool {
+	matched := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(r.base, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if r.re.MatchString(filepath.ToSlash(rel)) {
+			matched = !r.negate
+		}
+	}
+	return matched
+}
+
+// readGlobRules reads rules of ignore file relative to base.
+func readGlobRules(path, base string) ([]globRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rules := make([]globRule, 0)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		r, ok, err := newGlobRule(scanner.Text(), base)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, n, err)
+		}
+		if ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules, scanner.Err()
+}
+
+// newGlobRule compiles pattern of .gitignore syntax relative to base.
+// Comments and blank patterns are not rules.
+func newGlobRule(pattern, base string) (globRule, bool, error) {
+	r := globRule{base: base}
+
+	p := strings.TrimRight(strings.TrimSuffix(pattern, "\r"), " ")
+	if p == "" || strings.HasPrefix(p, "#") {
+		return r, false, nil
+	}
+	if strings.HasPrefix(p, "!") {
+		r.negate = true
+		p = p[1:]
+	}
+	p = strings.TrimPrefix(p, "\\")
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return r, false, nil
+	}
+
+	// Pattern without slash matches name at any depth.
+	prefix := "^"
+	if strings.Contains(p, "/") {
+		p = strings.TrimPrefix(p, "/")
+	} else {
+		prefix = "^(.*/)?"
+	}
+	re, err := regexp.Compile(prefix + globToRegexp(p) + "$")
+	if err != nil {
+		return r, false, fmt.Errorf("Bad glob. [%s] (%s)", pattern, err)
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// globToRegexp converts glob to regexp. ** matches any directories.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			j := strings.IndexByte(glob[i:], ']')
+			if j < 0 {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += j
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,296 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/yukimemi/file"
+)
+
+// TestMatchRules is test glob rules matching.
+func TestMatchRules(t *testing.T) {
+	base := filepath.Join("root", "dir")
+	cases := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		matched bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "sub/deep/a.log", false, true},
+		{"*.log", "a.log.txt", false, false},
+		{"a?c", "abc", false, true},
+		{"a?c", "a/c", false, false},
+		{"[ab].txt", "b.txt", false, true},
+		{"[!ab].txt", "b.txt", false, false},
+		{"/build", "build", true, true},
+		{"/build", "sub/build", true, false},
+		{"doc/*.md", "doc/a.md", false, true},
+		{"doc/*.md", "doc/sub/a.md", false, false},
+		{"doc/**/*.md", "doc/a.md", false, true},
+		{"doc/**/*.md", "doc/sub/deep/a.md", false, true},
+		{"**/vendor", "a/b/vendor", true, true},
+		{"src/**", "src/a/b.go", false, true},
+		{"node_modules/", "node_modules", true, true},
+		{"node_modules/", "node_modules", false, false},
+		{"a.go", "../a.go", false, false},
+	}
+	for _, tc := range cases {
+		r, ok, err := newGlobRule(tc.pattern, base)
+		if err != nil || !ok {
+			t.Fatalf("[%s]: Expected rule but actual: [%v] [%v]\n", tc.pattern, ok, err)
+		}
+		if m := matchRules([]globRule{r}, filepath.Join(base, filepath.FromSlash(tc.path)), tc.isDir); m != tc.matched {
+			t.Errorf("[%s] [%s]: Expected [%v] but actual: [%v]\n", tc.pattern, tc.path, tc.matched, m)
+		}
+	}
+
+	// The last matched rule wins.
+	rules := make([]globRule, 0)
+	for _, p := range []string{"# comment", "", "*.log", "!keep.log"} {
+		r, ok, err := newGlobRule(p, base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) != 2 || !matchRules(rules, filepath.Join(base, "a.log"), false) || matchRules(rules, filepath.Join(base, "keep.log"), false) {
+		t.Errorf("Expected negated rule but actual: [%v]\n", rules)
+	}
+}
+
+// TestGetCmdRunGlob is test getCmd.Run with globs and ignore files.
+func TestGetCmdRunGlob(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() {
+		columns, fileOnly, includes, excludes, excludeFrom, respectGitignore = nil, false, nil, nil, "", false
+	}()
+
+	// tree/.gitignore (*.log, build/), tree/sub/.ignore (!keep.log)
+	root := filepath.Join(tmp, "tree")
+	files := map[string]string{
+		".gitignore":        "*.log\nbuild/\n",
+		"a.go":              "",
+		"a.log":             "",
+		"build/out.go":      "",
+		"sub/.ignore":       "!keep.log\n",
+		"sub/b.go":          "",
+		"sub/keep.log":      "",
+		"sub/drop.log":      "",
+		"sub/deep/c.txt":    "",
+		".git/HEAD":         "",
+		"node_modules/x.js": "",
+	}
+	for p, data := range files {
+		p = filepath.Join(root, filepath.FromSlash(p))
+		err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(p, []byte(data), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	excludeFile := filepath.Join(tmp, "exclude")
+	err = ioutil.WriteFile(excludeFile, []byte("# dependencies\nnode_modules/\n"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"--include", "*.go"}, []string{"a.go", "build/out.go", "sub/b.go"}},
+		{[]string{"--include", "sub/**", "--exclude", "*.log"}, []string{"sub/.ignore", "sub/b.go", "sub/deep/c.txt"}},
+		{[]string{"--include", "*.js", "--exclude-from", excludeFile}, []string{}},
+		{[]string{"--respect-gitignore", "--exclude", "node_modules"}, []string{".gitignore", "a.go", "sub/.ignore", "sub/b.go", "sub/deep/c.txt", "sub/keep.log"}},
+	}
+	c := filepath.Join(tmp, getCsv1)
+	for _, tc := range cases {
+		RootCmd.SetArgs(append(append([]string{"get", "-f", "-c", "Rel", "-o", c}, tc.args...), root))
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rels := make([]string, 0)
+		if f, err := os.Open(c); err == nil {
+			records, err := newGetReader(f).ReadAll()
+			f.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range records[1:] {
+				rel, err := filepath.Rel(root, r[0])
+				if err != nil {
+					t.Fatal(err)
+				}
+				rels = append(rels, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(rels)
+		if fmt.Sprint(rels) != fmt.Sprint(tc.expected) {
+			t.Errorf("%v: Expected %v but actual: %v\n", tc.args, tc.expected, rels)
+		}
+		os.Remove(c)
+		includes, excludes, excludeFrom, respectGitignore = nil, nil, "", false
+	}
+}
+
+// TestGetCmdRunGlobSymlink is test getCmd.Run following linked directories with ignore files.
+func TestGetCmdRunGlobSymlink(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Symbolic link needs privilege on windows")
+	}
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, fileOnly, followSymlinks, respectGitignore = nil, false, false, false }()
+
+	// tree/bad and tree/link are links to target. (target/.gitignore is *.tmp)
+	// tree/z0 is walked while link is followed.
+	root := filepath.Join(tmp, "tree")
+	target := filepath.Join(tmp, "target")
+	files := map[string]string{
+		"tree/.gitignore":     "*.log\nbad/\n",
+		"tree/a.go":           "",
+		"tree/a.log":          "",
+		"tree/z0/e.go":        "",
+		"tree/z1/e.log":       "",
+		"target/.gitignore":   "*.tmp\n",
+		"target/b.go":         "",
+		"target/b.tmp":        "",
+		"target/c.log":        "",
+		"target/deep/d.go":    "",
+		"target/deep/d.tmp":   "",
+		"target/deep/.ignore": "!d.tmp\n",
+	}
+	for p, data := range files {
+		p = filepath.Join(tmp, filepath.FromSlash(p))
+		err = os.MkdirAll(filepath.Dir(p), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(p, []byte(data), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range []string{"bad", "link"} {
+		err = os.Symlink(target, filepath.Join(root, l))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := filepath.Join(tmp, getCsv1)
+	RootCmd.SetArgs([]string{"get", "-L", "-f", "--respect-gitignore", "-c", "Rel", "-o", c, root})
+	err = RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := newGetReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rels := make([]string, 0)
+	for _, r := range records[1:] {
+		rel, err := filepath.Rel(root, r[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+	sort.Strings(rels)
+	expected := []string{".gitignore", "a.go", "link/.gitignore", "link/b.go", "link/deep/.ignore", "link/deep/d.go", "link/deep/d.tmp", "z0/e.go"}
+	if fmt.Sprint(rels) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but actual: %v\n", expected, rels)
+	}
+}
+
+// TestWalkInfos is test walkInfos doesn't walk into skipped directories.
+func TestWalkInfos(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+
+	for i := 0; i < 10; i++ {
+		p := filepath.Join(tmp, "node_modules", fmt.Sprint("pkg", i), "index.js")
+		err := os.MkdirAll(filepath.Dir(p), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(p, nil, os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	walked := make([]string, 0)
+	infos, err := walkInfos(tmp, file.Option{Ignores: []string{"dir0$"}}, func(path string, f os.FileInfo) bool {
+		walked = append(walked, path)
+		return f.Name() == "node_modules"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := 0
+	for f := range infos {
+		if f.Err != nil {
+			t.Fatal(f.Err)
+		}
+		output++
+	}
+
+	// root, file0-2, node_modules, dir1, dir1/file1, dir2 and dir2/file2.
+	if len(walked) != 9 || output != 8 {
+		t.Errorf("Expected 9 walked and 8 output but actual: %d walked and %d output %v\n", len(walked), output, walked)
+	}
+	for _, p := range walked {
+		if strings.Contains(p, "pkg") || strings.Contains(p, "dir0") {
+			t.Errorf("Expected not to walk into skipped directories but actual: [%s]\n", p)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// hashAlgos is supported hash algorithms.
+var hashAlgos = []string{MD5, SHA1, SHA256, XXHASH}
+
+// hashWorkers is number of files hashed in parallel.
+var hashWorkers = runtime.NumCPU()
+
+// hashPriority is hash columns in order of preference for comparison.
+var hashPriority = []FileInfoValue{FileSHA256, FileSHA1, FileXXHash, FileMD5}
+
+// validateHashes checks given hash algorithm names.
+func validateHashes(algos []string) error {
+	seen := make(map[string]bool)
+	for _, algo := range algos {
+		if hashColumn(algo) == 0 {
+			return fmt.Errorf("Unknown hash algorithm. [%s] (%s)", algo, strings.Join(hashAlgos, ", "))
+		}
+		if seen[algo] {
+			return fmt.Errorf("Duplicate hash algorithm. [%s]", algo)
+		}
+		seen[algo] = true
+	}
+	return nil
+}
+
+// hashColumn returns FileInfoValue of hash algorithm. 0 if unknown.
+func hashColumn(algo string) FileInfoValue {
+	switch algo {
+	case MD5:
+		return FileMD5
+	case SHA1:
+		return FileSHA1
+	case SHA256:
+		return FileSHA256
+	case XXHASH:
+		return FileXXHash
+	}
+	return 0
+}
+
+func newHash(algo string) hash.Hash {
+	switch algo {
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	case SHA256:
+		return sha256.New()
+	case XXHASH:
+		return xxhash.New()
+	}
+	return nil
+}
+
+// hashJob is file to hash with its result slot.
+type hashJob struct {
+	fi   FileInfo
+	done chan FileInfo
+}
+
+// hashFileInfos computes hashes of files received from fi with bounded workers.
+// Results are output in received order, so output is reproducible.
+func hashFileInfos(fi chan FileInfo, algos []string) chan FileInfo {
+
+	var (
+		hashed = make(chan FileInfo)
+		jobs   = make(chan hashJob)
+		// slots is result slots in received order. Buffer bounds jobs in flight.
+		slots = make(chan chan FileInfo, hashWorkers)
+	)
+
+	if len(algos) == 0 {
+		return fi
+	}
+
+	for i := 0; i < hashWorkers; i++ {
+		go func() {
+			for j := range jobs {
+				if j.fi.Type == FILE {
+					err := hashFileInfo(&j.fi, algos)
+					if err != nil {
+						if !errSkip {
+							log.Fatalln(err)
+						}
+						fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+					}
+				}
+				j.done <- j.fi
+			}
+		}()
+	}
+
+	// Dispatch jobs with result slots.
+	go func() {
+		for f := range fi {
+			done := make(chan FileInfo, 1)
+			slots <- done
+			jobs <- hashJob{fi: f, done: done}
+		}
+		close(jobs)
+		close(slots)
+	}()
+
+	// Output results in order.
+	go func() {
+		for done := range slots {
+			hashed <- <-done
+		}
+		close(hashed)
+	}()
+
+	return hashed
+}
+
+// hashFileInfo reads file once and sets hashes of algos to fi.
+func hashFileInfo(fi *FileInfo, algos []string) error {
+	sums, err := hashFile(fi.Abs, algos)
+	if err != nil {
+		return err
+	}
+	for algo, sum := range sums {
+		switch hashColumn(algo) {
+		case FileMD5:
+			fi.MD5 = sum
+		case FileSHA1:
+			fi.SHA1 = sum
+		case FileSHA256:
+			fi.SHA256 = sum
+		case FileXXHash:
+			fi.XXHash = sum
+		}
+	}
+	return nil
+}
+
+// hashFile returns hex encoded hashes of path contents by algorithm.
+func hashFile(path string, algos []string) (map[string]string, error) {
+
+	var (
+		hs      = make([]hash.Hash, 0)
+		writers = make([]io.Writer, 0)
+		sums    = make(map[string]string)
+	)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	for _, algo := range algos {
+		h := newHash(algo)
+		hs = append(hs, h)
+		writers = append(writers, h)
+	}
+	_, err = io.Copy(io.MultiWriter(writers...), f)
+	if err != nil {
+		return nil, err
+	}
+	for i, algo := range algos {
+		sums[algo] = hex.EncodeToString(hs[i].Sum(nil))
+	}
+	return sums, nil
+}
@@ -19,7 +19,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -39,12 +38,49 @@ const (
 	FILE = "file"
 	// DIR is directory.
 	DIR = "directory"
+	// SYMLINK is symbolic link.
+	SYMLINK = "symlink"
 	// COUNT is file count.
 	COUNT = "Count"
 	// UTF8 is csv encoding.
 	UTF8 = "utf8"
 	// SJIS is csv encoding.
 	SJIS = "sjis"
+	// MD5 is md5 hash algorithm.
+	MD5 = "md5"
+	// SHA1 is sha1 hash algorithm.
+	SHA1 = "sha1"
+	// SHA256 is sha256 hash algorithm.
+	SHA256 = "sha256"
+	// XXHASH is xxHash (XXH64) hash algorithm.
+	XXHASH = "xxhash"
+	// CSV is output format.
+	CSV = "csv"
+	// JSON is output format.
+	JSON = "json"
+	// JSONL is output format. (JSON Lines)
+	JSONL = "jsonl"
+	// TSV is output format.
+	TSV = "tsv"
+	// MARKDOWN is output format. (Markdown table)
+	MARKDOWN = "markdown"
+	// RFC3339 is time format name. (RFC 3339 with nanoseconds)
+	RFC3339 = "rfc3339"
+	// UNIX is time format name. (Unix epoch seconds)
+	UNIX = "unix"
+	// UNIXNANO is time format name. (Unix epoch nanoseconds)
+	UNIXNANO = "unixnano"
+	// STDOUT is output path of standard output.
+	STDOUT = "-"
+
+	// FormatVersion is version of get csv format.
+	// Version 1 is the fixed 8 columns without format marker line.
+	FormatVersion = 2
+	// formatMarker is prefix of format marker line before csv header of get.
+	formatMarker = "#gfi-format "
+
+	// timeLayout is default time format of output.
+	timeLayout = "2006/01/02 15:04:05.000"
 )
 
 const (
@@ -64,6 +100,44 @@ const (
 	FileMode
 	// FileType is file or directory.
 	FileType
+	// FileMD5 is md5 hash of file contents.
+	FileMD5
+	// FileSHA1 is sha1 hash of file contents.
+	FileSHA1
+	// FileSHA256 is sha256 hash of file contents.
+	FileSHA256
+	// FileXXHash is xxHash of file contents.
+	FileXXHash
+	// FileOwner is owner user name.
+	FileOwner
+	// FileGroup is owner group name.
+	FileGroup
+	// FileUID is owner user id.
+	FileUID
+	// FileGID is owner group id.
+	FileGID
+	// FileATime is file accessed time.
+	FileATime
+	// FileCTime is file status changed time.
+	FileCTime
+	// FileBTime is file created (birth) time.
+	FileBTime
+	// FileLinkTarget is symbolic link target.
+	FileLinkTarget
+	// FileBroken is whether symbolic link target is missing.
+	FileBroken
+	// FileDev is device id.
+	FileDev
+	// FileInode is inode number.
+	FileInode
+	// FileNlink is number of hard links.
+	FileNlink
+	// FileDiskUsage is allocated disk size.
+	FileDiskUsage
+	// FileHash is contents diff (any hash algorithm).
+	FileHash
+	// FileRenamed is renamed or moved diff.
+	FileRenamed
 	// FileMax is Max
 	FileMax = iota
 )
@@ -85,6 +159,10 @@ const (
 	DirFileCount
 	// DirDirCount is directory count or directory.
 	DirDirCount
+	// DirDiskUsage is allocated disk size of directory.
+	DirDiskUsage
+	// DirDepth is depth from root directory.
+	DirDepth
 	// DirMax is Max
 	DirMax = iota
 )
@@ -96,6 +174,7 @@ var (
 	fileOnly bool
 	dirOnly  bool
 	sjisOut  bool
+	format   string
 	matches  []string
 	ignores  []string
 	sorts    string
@@ -117,6 +196,29 @@ type FileInfo struct {
 	Size string
 	Mode string
 	Type string
+	// Hashes. (Empty if not computed)
+	MD5    string
+	SHA1   string
+	SHA256 string
+	XXHash string
+	// Owner. (Empty if not supported)
+	Owner string
+	Group string
+	UID   string
+	GID   string
+	// Extended times. (Empty if not supported)
+	ATime string
+	CTime string
+	BTime string
+	// Symbolic link. (Empty if not symbolic link)
+	LinkTarget string
+	Broken     string
+	// Device and inode. (Empty if not supported)
+	Dev   string
+	Inode string
+	Nlink string
+	// Allocated disk size. (Empty if not supported)
+	DiskUsage string
 }
 
 // DirInfo is file infomation.
@@ -129,6 +231,8 @@ type DirInfo struct {
 	Size      string
 	FileCount int64
 	DirCount  int64
+	DiskUsage string
+	Depth     int
 }
 
 // FileInfos is FileInfo slice.
@@ -140,13 +244,16 @@ type DirInfos []DirInfo
 type records [][]string
 
 // RootCmd represents the base command when called without any subcommands
-var RootCmd = &cobra.Command{Use: "gfi"}
+var RootCmd = &cobra.Command{
+	Use:              "gfi",
+	PersistentPreRun: initOutput,
+}
 
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(-1)
 	}
 }
@@ -165,21 +272,33 @@ func init() {
 		log.Fatalln(err)
 	}
 
-	// Output csv path.
-	csvPath := filepath.Join(pwd, time.Now().Format("20060102-150405.000")+".csv")
-	RootCmd.PersistentFlags().StringVarP(&out, "out", "o", csvPath, "Csv output path")
+	// Output path.
+	outPath := filepath.Join(pwd, time.Now().Format("20060102-150405.000")+".csv")
+	RootCmd.PersistentFlags().StringVarP(&out, "out", "o", outPath, "Output path (\"-\" is stdout. default is stdout if piped)")
+	// Time format and zone.
+	RootCmd.PersistentFlags().StringVar(&timeFormat, "time-format", timeLayout, "Time format of output (Go layout, rfc3339, unix, unixnano)")
+	RootCmd.PersistentFlags().StringVar(&timeZone, "tz", "Local", "Time zone of output and of input time without zone (ex: UTC, Asia/Tokyo)")
 	// Verbose flag.
 	RootCmd.PersistentFlags().BoolVarP(&silent, "silent", "S", false, "Print no count")
 	// File only flag.
 	RootCmd.PersistentFlags().BoolVarP(&fileOnly, "file", "f", false, "Get information file only")
 	// Directory only flag.
 	RootCmd.PersistentFlags().BoolVarP(&dirOnly, "dir", "d", false, "Get information directory only")
-	// Whether output csv in ShiftJIS encoding.
-	RootCmd.PersistentFlags().BoolVarP(&sjisOut, "sjisout", "j", false, "Output csv in ShiftJIS encoding")
+	// Whether output in ShiftJIS encoding.
+	RootCmd.PersistentFlags().BoolVarP(&sjisOut, "sjisout", "j", false, "Output in ShiftJIS encoding")
+	// Output format.
+	RootCmd.PersistentFlags().StringVarP(&format, "format", "F", CSV, "Output format ("+strings.Join(formatNames(), ", ")+")")
 	// Matches list.
 	RootCmd.PersistentFlags().StringArrayVarP(&matches, "match", "m", nil, "Match list (Regexp)")
 	// Ignores list.
 	RootCmd.PersistentFlags().StringArrayVarP(&ignores, "ignore", "i", nil, "Ignore list (Regexp)")
+	// Size and time range filters.
+	RootCmd.PersistentFlags().StringVar(&minSize, "min-size", "", "Minimum size (ex: 100, 10K, 1.5M, 2G)")
+	RootCmd.PersistentFlags().StringVar(&maxSize, "max-size", "", "Maximum size (ex: 100, 10K, 1.5M, 2G)")
+	RootCmd.PersistentFlags().BoolVar(&byDiskUsage, "by-disk-usage", false, "Filter --min-size and --max-size by DiskUsage instead of Size")
+	RootCmd.PersistentFlags().StringVar(&newerThan, "newer-than", "", "Modified after duration ago or time (ex: 36h, 7d, 2w, 2017-10-01)")
+	RootCmd.PersistentFlags().StringVar(&olderThan, "older-than", "", "Modified before duration ago or time (ex: 36h, 7d, 2w, 2017-10-01)")
+	RootCmd.PersistentFlags().StringVar(&newerThanFile, "newer-than-file", "", "Modified after the file")
 
 	// log setting.
 	log.SetFlags(log.Lshortfile)
@@ -197,8 +316,48 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
+}
+
+// initOutput checks output format and time settings, and sets extension of default output path.
+func initOutput(cmd *cobra.Command, args []string) {
+	err := validateFormat(format)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = initTime()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	err = initFilter()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !cmd.Flags().Changed("out") {
+		if isPiped(os.Stdout) {
+			out = STDOUT
+		} else {
+			out = strings.TrimSuffix(out, filepath.Ext(out)) + "." + recordFormats[format].ext
+		}
+	}
+}
+
+// isPiped returns whether f is not a terminal.
+func isPiped(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice == 0
+}
+
+// outName returns output path for message.
+func outName() string {
+	if out == STDOUT {
+		return "stdout"
 	}
+	return out
 }
 
 // Len returns FileInfos length.
@@ -231,33 +390,6 @@ func (d DirInfos) Swap(i, j int) {
 	d[i], d[j] = d[j], d[i]
 }
 
-// Len returns records length.
-func (r records) Len() int {
-	return len(r)
-}
-
-// Less returns which record is less.
-func (r records) Less(i, j int) bool {
-	indexes := strings.Split(sorts, ",")
-	for _, index := range indexes {
-		ii, err := strconv.Atoi(index)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		if r[i][ii] < r[j][ii] {
-			return true
-		} else if r[i][ii] > r[j][ii] {
-			return false
-		}
-	}
-	return false
-}
-
-// Swap is records swap func.
-func (r records) Swap(i, j int) {
-	r[i], r[j] = r[j], r[i]
-}
-
 func (fiv FileInfoValue) String() string {
 	switch fiv {
 	case FileFull:
@@ -276,6 +408,44 @@ func (fiv FileInfoValue) String() string {
 		return "Mode"
 	case FileType:
 		return "Type"
+	case FileMD5:
+		return "MD5"
+	case FileSHA1:
+		return "SHA1"
+	case FileSHA256:
+		return "SHA256"
+	case FileXXHash:
+		return "XXHash"
+	case FileOwner:
+		return "Owner"
+	case FileGroup:
+		return "Group"
+	case FileUID:
+		return "UID"
+	case FileGID:
+		return "GID"
+	case FileATime:
+		return "ATime"
+	case FileCTime:
+		return "CTime"
+	case FileBTime:
+		return "BTime"
+	case FileLinkTarget:
+		return "LinkTarget"
+	case FileBroken:
+		return "Broken"
+	case FileDev:
+		return "Dev"
+	case FileInode:
+		return "Inode"
+	case FileNlink:
+		return "Nlink"
+	case FileDiskUsage:
+		return "DiskUsage"
+	case FileHash:
+		return "Hash"
+	case FileRenamed:
+		return "Renamed"
 	}
 	return ""
 }
@@ -298,6 +468,10 @@ func (div DirInfoValue) String() string {
 		return "FileCount"
 	case DirDirCount:
 		return "DirCount"
+	case DirDiskUsage:
+		return "DiskUsage"
+	case DirDepth:
+		return "Depth"
 	}
 	return ""
 }
@@ -15,7 +15,9 @@
 package cmd
 
 import (
+	"encoding/csv"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -53,6 +55,13 @@ func setup() string {
 	return temp
 }
 
+// newGetReader returns csv reader of get output. Format marker line is skipped as comment.
+func newGetReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	return reader
+}
+
 func shutdown(temp string) {
 	os.RemoveAll(temp)
 }
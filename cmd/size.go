@@ -15,22 +15,43 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"strings"
 	"sync"
 
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
-
 	"github.com/spf13/cobra"
 	"github.com/yukimemi/core"
 	"github.com/yukimemi/file"
 )
 
+var (
+	// dirColumns is output columns of size.
+	dirColumns []DirInfoValue
+
+	// Cmd options.
+	uniqueInodes bool
+	maxDepth     int
+)
+
+// dirTotal is totals of directory tree.
+type dirTotal struct {
+	info      file.Info
+	parent    *dirTotal
+	depth     int
+	size      int64
+	usage     int64
+	fileCount int64
+	dirCount  int64
+	// inodes is counted hard linked files.
+	inodes map[string]bool
+	// output is whether matched and in max depth.
+	output bool
+}
+
 // SizeInfo is directory size info.
 type SizeInfo struct {
 	path string
@@ -42,6 +63,7 @@ var sizeCmd = &cobra.Command{
 	Use:   "size path/to/dir",
 	Short: "Get directory size",
 	Long: `Get directory size command.
+Directories are output after their subdirectories.
 For example:
 
 	gfi size path/to/dir
@@ -55,20 +77,31 @@ func init() {
 
 	// Skip flag.
 	sizeCmd.Flags().BoolVarP(&errSkip, "err", "e", false, "Skip getting directory information on error")
+	// Output columns.
+	sizeCmd.Flags().StringSliceVarP(&columns, "columns", "c", nil, "Output column names with comma separated (ex: Rel,Size,FileCount)")
+	// Count hard links once.
+	sizeCmd.Flags().BoolVarP(&uniqueInodes, "unique-inodes", "u", false, "Count size of hard linked files once per directory tree")
+	// Glob filters.
+	addGlobFlags(sizeCmd)
+	// Output depth limit.
+	sizeCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "Output directories only up to depth N from root (totals are still recursive)")
 	// Sort with target column for csv.
-	sizeCmd.Flags().StringVarP(&sorts, "sorts", "s", "", "Sort target column number with commma sepalated (ex: 1,2,0)")
+	sizeCmd.Flags().StringVarP(&sorts, "sorts", "s", "", "Sort columns with comma separated. Column number or name with :num, :size, :time, :str or :desc (ex: Size:desc,1)")
 }
 
 func executeSize(cmd *cobra.Command, args []string) {
 
 	var (
-		err error
+		err  error
+		keys []sortKey
 
 		di       = make(chan DirInfo)
 		csvArray = make(records, 0)
 		wg       = new(sync.WaitGroup)
 	)
 
+	cnt = 0
+
 	if len(args) == 0 {
 		cmd.Help()
 		return
@@ -79,11 +112,26 @@ func executeSize(cmd *cobra.Command, args []string) {
 		log.Fatalln(err)
 	}
 
+	// Check columns and sort keys.
+	dirColumns, err = getDirColumns(columns)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	header := getDirCsvHeader()
+	// Sorts is shared with other commands, so only given sorts are used.
+	customSort := cmd.Flags().Changed("sorts") && sorts != ""
+	if customSort {
+		keys, err = parseSortKeys(sorts, header, func(i int) ValueKind { return kindOf(header[i]) })
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
 	for _, root := range args {
 		wg.Add(1)
 		go func(root string) {
 			defer wg.Done()
-			err = getDirInfo(root, di)
+			err := getDirInfo(root, di)
 			if err != nil {
 				log.Fatalln(err)
 			}
@@ -96,24 +144,9 @@ func executeSize(cmd *cobra.Command, args []string) {
 		close(di)
 	}()
 
-	// Create output csv file.
-	os.MkdirAll(filepath.Dir(out), os.ModePerm)
-	c, err := os.Create(out)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer c.Close()
-	var writer *csv.Writer
-	if sjisOut {
-		writer = csv.NewWriter(transform.NewWriter(c, japanese.ShiftJIS.NewEncoder()))
-	} else {
-		writer = csv.NewWriter(c)
-	}
-	writer.Comma = ','
-	writer.UseCRLF = true
-
-	// Write header.
-	err = writer.Write(getDirCsvHeader())
+	// Output header and records.
+	writer := newOutput(out, header, headerKinds(header))
+	err = writer.Open()
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -124,7 +157,7 @@ func executeSize(cmd *cobra.Command, args []string) {
 		if !silent {
 			fmt.Fprintf(os.Stderr, "Count: %d\r", cnt)
 		}
-		if sorts != "" {
+		if customSort {
 			csvArray = append(csvArray, dirInfoToCsv(d))
 		} else {
 			err = writer.Write(dirInfoToCsv(d))
@@ -135,8 +168,8 @@ func executeSize(cmd *cobra.Command, args []string) {
 	}
 
 	// sort FileInfos if sort flag set.
-	if sorts != "" {
-		sort.Sort(csvArray)
+	if customSort {
+		sortRecords(csvArray, keys)
 		for _, v := range csvArray {
 			err = writer.Write(v)
 			if err != nil {
@@ -144,90 +177,257 @@ func executeSize(cmd *cobra.Command, args []string) {
 			}
 		}
 	}
-	writer.Flush()
+	err = writer.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
 	if cnt == 0 {
-		fmt.Println("There is no information to get.")
-		c.Close()
-		os.RemoveAll(out)
+		fmt.Fprintln(os.Stderr, "There is no information to get.")
 	} else {
-		fmt.Printf("Write to [%s]. ([%d] row)\n", out, cnt)
+		fmt.Fprintf(os.Stderr, "Write to [%s]. ([%d] row)\n", outName(), cnt)
 	}
 }
 
+// getDirInfo gets directory information of root by one walk.
+// Directories are output after their subdirectories whatever columns and filters are given.
 func getDirInfo(root string, di chan DirInfo) error {
+	g, err := newGlobFilter(root)
+	if err != nil {
+		return err
+	}
+	return walkDirInfo(root, g, di)
+}
+
+// walkDirInfo gets directory information of root by one walk.
+// Hard linked files are counted once per directory tree if uniqueInodes.
+// Matches select counted files and directories.
+// Only totals of directories being walked are kept, so directories are output after their subdirectories.
+func walkDirInfo(root string, g *globFilter, di chan DirInfo) error {
 
 	var (
-		err  error
-		dirs chan file.DirInfo
+		err   error
+		match *regexp.Regexp
+
+		// stack is directories being walked from root.
+		stack = make([]*dirTotal, 0)
 
 		opt = file.Option{
-			Matches: matches,
 			Ignores: ignores,
 			Recurse: true,
 		}
 	)
 
-	dirs, err = file.GetDirInfos(root, opt)
+	// Unmatched directories are walked for totals of matched descendants.
+	if len(matches) != 0 {
+		match, err = core.CompileStrs(matches)
+		if err != nil {
+			return err
+		}
+	}
 
+	infos, err := walkInfos(root, opt, func(path string, f os.FileInfo) bool {
+		return g.skip(path, path, f)
+	})
 	if err != nil {
 		return err
 	}
 
-	for d := range dirs {
-		var dInfo DirInfo
-
-		if d.Err != nil {
+	for f := range infos {
+		if f.Err != nil {
 			if errSkip {
-				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", d.Err)
+				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", f.Err)
 				continue
 			}
-			return d.Err
+			return f.Err
 		}
-		dInfo.Abs, err = filepath.Abs(d.Path)
-		if err != nil {
-			if errSkip {
-				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
-				continue
+
+		// Directories out of path are walked. (pre-order walk)
+		dir := filepath.Dir(filepath.Clean(f.Path))
+		for len(stack) != 0 && filepath.Clean(stack[len(stack)-1].info.Path) != dir {
+			err = sendDirInfo(stack[len(stack)-1], di)
+			if err != nil {
+				return err
 			}
-			return err
+			stack = stack[:len(stack)-1]
 		}
-		dInfo.Full, err = filepath.Abs(file.ShareToAbs(d.Path))
-		if err != nil {
-			if errSkip {
-				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+		var parent *dirTotal
+		if len(stack) != 0 {
+			parent = stack[len(stack)-1]
+		}
+
+		matched := match == nil || match.MatchString(f.Path)
+		usage, _ := fileDiskUsage(f.Fi)
+		if f.Fi.IsDir() {
+			d := &dirTotal{info: f, parent: parent}
+			if parent != nil {
+				d.depth = parent.depth + 1
+			}
+			stack = append(stack, d)
+			if !matched {
 				continue
 			}
+			d.usage = usage
+			d.output = maxDepth < 0 || d.depth <= maxDepth
+			for p := parent; p != nil; p = p.parent {
+				p.dirCount++
+				p.usage += usage
+			}
+			continue
+		}
+		if !matched {
+			continue
+		}
+		key, linked := hardLinkKey(f.Fi)
+		linked = linked && uniqueInodes
+		for p := parent; p != nil; p = p.parent {
+			p.fileCount++
+			if linked {
+				if p.inodes[key] {
+					continue
+				}
+				if p.inodes == nil {
+					p.inodes = make(map[string]bool)
+				}
+				p.inodes[key] = true
+			}
+			p.size += f.Fi.Size()
+			p.usage += usage
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		err = sendDirInfo(stack[i], di)
+		if err != nil {
 			return err
 		}
-		dInfo.Rel = d.Path
-		dInfo.Name = d.Fi.Name()
-		dInfo.Time = d.Fi.ModTime().Format("2006/01/02 15:04:05.000")
-		dInfo.Size = fmt.Sprint(d.DirSize)
-		dInfo.FileCount = d.FileCount
-		dInfo.DirCount = d.DirCount
+	}
+	return nil
+}
+
+// sendDirInfo sends DirInfo of walked directory d to di if output and in filter ranges.
+func sendDirInfo(d *dirTotal, di chan DirInfo) error {
+
+	var (
+		err   error
+		dInfo DirInfo
+	)
+
+	if !d.output {
+		return nil
+	}
+	dInfo.Abs, err = filepath.Abs(d.info.Path)
+	if err != nil {
+		if errSkip {
+			fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+			return nil
+		}
+		return err
+	}
+	dInfo.Full, err = filepath.Abs(file.ShareToAbs(d.info.Path))
+	if err != nil {
+		if errSkip {
+			fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+			return nil
+		}
+		return err
+	}
+	dInfo.Rel = d.info.Path
+	dInfo.Name = d.info.Fi.Name()
+	dInfo.Time = formatTime(d.info.Fi.ModTime())
+	dInfo.Size = fmt.Sprint(d.size)
+	dInfo.FileCount = d.fileCount
+	dInfo.DirCount = d.dirCount
+	dInfo.Depth = d.depth
+	if _, ok := fileDiskUsage(d.info.Fi); ok {
+		dInfo.DiskUsage = fmt.Sprint(d.usage)
+	}
+	if passFilter(dInfo.Size, dInfo.DiskUsage, d.info.Fi.ModTime()) {
 		di <- dInfo
 	}
 	return nil
 }
 
 func dirInfoToCsv(di DirInfo) []string {
-	a := make([]string, DirMax)
-	a[DirFull-1] = di.Full
-	a[DirRel-1] = di.Rel
-	a[DirAbs-1] = di.Abs
-	a[DirName-1] = di.Name
-	a[DirTime-1] = di.Time
-	a[DirSize-1] = di.Size
-	a[DirFileCount-1] = fmt.Sprint(di.FileCount)
-	a[DirDirCount-1] = fmt.Sprint(di.DirCount)
+	a := make([]string, 0, len(dirColumns))
+	for _, div := range dirColumns {
+		a = append(a, di.value(div))
+	}
 	return a
 }
 
 func getDirCsvHeader() []string {
-	var div DirInfoValue
 	header := make([]string, 0)
-	for div = 1; div <= DirMax; div++ {
+	for _, div := range dirColumns {
 		header = append(header, div.String())
 	}
 	return header
 }
+
+// getDirColumns returns output columns of column names. Default columns if names is empty.
+func getDirColumns(names []string) ([]DirInfoValue, error) {
+	var div DirInfoValue
+
+	columns := make([]DirInfoValue, 0)
+	if len(names) == 0 {
+		for div = 1; div <= DirDirCount; div++ {
+			columns = append(columns, div)
+		}
+		return columns, nil
+	}
+
+	for _, name := range names {
+		div = dirColumn(name)
+		if div == 0 {
+			return nil, fmt.Errorf("Unknown column. [%s]", name)
+		}
+		columns = append(columns, div)
+	}
+	return columns, nil
+}
+
+// containsDirColumn returns whether columns contains div.
+func containsDirColumn(columns []DirInfoValue, div DirInfoValue) bool {
+	for _, c := range columns {
+		if c == div {
+			return true
+		}
+	}
+	return false
+}
+
+// dirColumn returns DirInfoValue of column name. (Case insensitive) 0 if unknown.
+func dirColumn(name string) DirInfoValue {
+	var div DirInfoValue
+	for div = 1; div <= DirMax; div++ {
+		if strings.EqualFold(div.String(), name) {
+			return div
+		}
+	}
+	return 0
+}
+
+func (di DirInfo) value(div DirInfoValue) string {
+	switch div {
+	case DirFull:
+		return di.Full
+	case DirRel:
+		return di.Rel
+	case DirAbs:
+		return di.Abs
+	case DirName:
+		return di.Name
+	case DirTime:
+		return di.Time
+	case DirSize:
+		return di.Size
+	case DirFileCount:
+		return fmt.Sprint(di.FileCount)
+	case DirDirCount:
+		return fmt.Sprint(di.DirCount)
+	case DirDiskUsage:
+		return di.DiskUsage
+	case DirDepth:
+		return fmt.Sprint(di.Depth)
+	}
+	return ""
+}
@@ -0,0 +1,364 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// runSize runs sizeCmd and returns output records by Name.
+func runSize(t *testing.T, c string, args ...string) map[string]map[string]string {
+	RootCmd.SetArgs(append([]string{"size", "-o", c}, args...))
+	err := RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := make(map[string]map[string]string)
+	for _, r := range records[1:] {
+		d := make(map[string]string)
+		for i, name := range records[0] {
+			d[name] = r[i]
+		}
+		dirs[d["Name"]] = d
+	}
+	return dirs
+}
+
+// TestSizeCmdRunUniqueInodes is test sizeCmd.Run with hard linked files.
+func TestSizeCmdRunUniqueInodes(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, uniqueInodes = nil, false }()
+
+	// links/a/data, links/a/copy and links/b/data are the same inode.
+	root := filepath.Join(tmp, "links")
+	for _, d := range []string{"a", "b"} {
+		err = os.MkdirAll(filepath.Join(root, d), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := filepath.Join(root, "a", "data")
+	err = ioutil.WriteFile(data, []byte("0123456789"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{filepath.Join(root, "a", "copy"), filepath.Join(root, "b", "data")} {
+		err = os.Link(data, p)
+		if err != nil {
+			t.Skip(err)
+		}
+	}
+	if _, _, nlink, ok := fileID(mustStat(t, data)); !ok || nlink != 3 {
+		t.Skip("Hard link count is not supported")
+	}
+
+	c := filepath.Join(tmp, getCsv1)
+	cases := []struct {
+		args  []string
+		sizes map[string]string
+	}{
+		{[]string{"-c", "Name,Size,FileCount"}, map[string]string{"links": "30", "a": "20", "b": "10"}},
+		{[]string{"-c", "Name,Size,FileCount", "-u"}, map[string]string{"links": "10", "a": "10", "b": "10"}},
+	}
+	for _, tc := range cases {
+		dirs := runSize(t, c, append(tc.args, root)...)
+		for name, size := range tc.sizes {
+			if dirs[name]["Size"] != size {
+				t.Errorf("%v: Expected [%s] size [%s] but actual: [%v]\n", tc.args, name, size, dirs[name])
+			}
+		}
+		if dirs["links"]["FileCount"] != "3" {
+			t.Errorf("%v: Expected file count [3] but actual: [%v]\n", tc.args, dirs["links"])
+		}
+		columns, uniqueInodes = nil, false
+	}
+}
+
+// TestSizeCmdRunDiskUsage is test sizeCmd.Run with a sparse file.
+func TestSizeCmdRunDiskUsage(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("Disk usage is not supported on windows")
+	}
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns = nil }()
+
+	root := filepath.Join(tmp, "sparse")
+	err = os.MkdirAll(root, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(root, "image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Truncate(10 << 20)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := runSize(t, filepath.Join(tmp, getCsv1), "-c", "Name,Size,DiskUsage", root)
+	if dirs["sparse"]["Size"] != fmt.Sprint(10<<20) {
+		t.Errorf("Expected apparent size [%d] but actual: [%v]\n", 10<<20, dirs["sparse"])
+	}
+	usage, err := strconv.ParseInt(dirs["sparse"]["DiskUsage"], 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage >= 10<<20 {
+		t.Errorf("Expected disk usage less than apparent size but actual: [%v]\n", dirs["sparse"])
+	}
+}
+
+// TestSizeCmdRunMaxDepth is test sizeCmd.Run with depth limit.
+func TestSizeCmdRunMaxDepth(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, maxDepth = nil, -1 }()
+
+	// deep/a/b/c/data
+	root := filepath.Join(tmp, "deep")
+	err = os.MkdirAll(filepath.Join(root, "a", "b", "c"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(root, "a", "b", "c", "data"), []byte("data"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := runSize(t, filepath.Join(tmp, getCsv1), "-c", "Name,Size,FileCount,DirCount,Depth", "--max-depth", "1", root)
+	if len(dirs) != 2 {
+		t.Fatalf("Expected 2 directories but actual: [%v]\n", dirs)
+	}
+	expected := map[string]map[string]string{
+		"deep": {"Name": "deep", "Size": "4", "FileCount": "1", "DirCount": "3", "Depth": "0"},
+		"a":    {"Name": "a", "Size": "4", "FileCount": "1", "DirCount": "2", "Depth": "1"},
+	}
+	for name, e := range expected {
+		if fmt.Sprint(dirs[name]) != fmt.Sprint(e) {
+			t.Errorf("Expected [%v] but actual: [%v]\n", e, dirs[name])
+		}
+	}
+}
+
+// TestSizeCmdRunMatch is test sizeCmd.Run with matches selecting counted files.
+func TestSizeCmdRunMatch(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, matches, uniqueInodes = nil, nil, false }()
+
+	// match/a/big.bin (100) and match/a/small.txt (10).
+	root := filepath.Join(tmp, "match")
+	err = os.MkdirAll(filepath.Join(root, "a"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"big.bin": 100, "small.txt": 10} {
+		err = ioutil.WriteFile(filepath.Join(root, "a", name), make([]byte, size), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := runSize(t, filepath.Join(tmp, getCsv1), "-c", "Name,Size,FileCount", "-m", `(match|/a|\.txt)$`, root)
+	expected := map[string]map[string]string{
+		"match": {"Name": "match", "Size": "10", "FileCount": "1"},
+		"a":     {"Name": "a", "Size": "10", "FileCount": "1"},
+	}
+	if fmt.Sprint(dirs) != fmt.Sprint(expected) {
+		t.Errorf("Expected [%v] but actual: [%v]\n", expected, dirs)
+	}
+}
+
+// TestSizeCmdRunSorts is test sizeCmd.Run sorts only with given sorts.
+func TestSizeCmdRunSorts(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() {
+		columns, sorts = nil, "0"
+		sizeCmd.Flags().Lookup("sorts").Changed = false
+	}()
+
+	// sorts/z is output before sorts without sort.
+	root := filepath.Join(tmp, "sorts")
+	err = os.MkdirAll(filepath.Join(root, "z"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{"-c", "Name"}, []string{"z", "sorts"}},
+		{[]string{"-c", "Name", "-s", "Name"}, []string{"sorts", "z"}},
+	}
+	c := filepath.Join(tmp, getCsv1)
+	for _, tc := range cases {
+		// Sorts of other commands are left.
+		sorts = "0"
+		RootCmd.SetArgs(append(append([]string{"size", "-o", c}, tc.args...), root))
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns = nil
+		f, err := os.Open(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0)
+		for _, r := range records[1:] {
+			names = append(names, r[0])
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tc.expected) {
+			t.Errorf("%v: Expected %v but actual: %v\n", tc.args, tc.expected, names)
+		}
+	}
+}
+
+// TestSizeCmdRunOrder is test sizeCmd.Run outputs in the same order with any flags.
+func TestSizeCmdRunOrder(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, excludes, uniqueInodes = nil, nil, false }()
+
+	// order/a/b, order/a/c and order/d with a file each.
+	root := filepath.Join(tmp, "order")
+	for _, d := range []string{filepath.Join("a", "b"), filepath.Join("a", "c"), "d"} {
+		err = os.MkdirAll(filepath.Join(root, d), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(root, d, "data"), []byte("data"), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// rels returns Rel column in output order.
+	rels := func(args ...string) []string {
+		c := filepath.Join(tmp, getCsv1)
+		RootCmd.SetArgs(append(append([]string{"size", "-o", c}, args...), root))
+		err := RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns, excludes, uniqueInodes = nil, nil, false
+		f, err := os.Open(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs := make([]string, 0)
+		for _, r := range records[1:] {
+			rs = append(rs, r[0])
+		}
+		return rs
+	}
+
+	// Directories are output after their subdirectories.
+	expected := []string{
+		filepath.Join(root, "a", "b"),
+		filepath.Join(root, "a", "c"),
+		filepath.Join(root, "a"),
+		filepath.Join(root, "d"),
+		root,
+	}
+	cases := [][]string{
+		{"-c", "Rel"},
+		{"-c", "Rel", "-u"},
+		{"-c", "Rel,DiskUsage"},
+		{"-c", "Rel", "--exclude", "*.csv"},
+	}
+	for _, args := range cases {
+		if actual := rels(args...); fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("%v: Expected %v but actual: %v\n", args, expected, actual)
+		}
+	}
+}
+
+func mustStat(t *testing.T, p string) os.FileInfo {
+	f, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
@@ -0,0 +1,203 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sortType is value type of sort key.
+type sortType int
+
+const (
+	sortString sortType = iota
+	sortNumber
+	sortSize
+	sortTime
+)
+
+// sortTypes is sort type of key option name.
+var sortTypes = map[string]sortType{
+	"str":  sortString,
+	"num":  sortNumber,
+	"size": sortSize,
+	"time": sortTime,
+}
+
+// sortKey is a sort column of records.
+type sortKey struct {
+	index int
+	typ   sortType
+	desc  bool
+}
+
+// sortValue is parsed value of sort key. Unparsable values are sorted last as strings.
+type sortValue struct {
+	ok bool
+	n  float64
+	t  time.Time
+	s  string
+}
+
+// recordSorter sorts records by keys with parsed values.
+type recordSorter struct {
+	rs   records
+	vals [][]sortValue
+	keys []sortKey
+}
+
+// parseSortKeys parses comma separated sort keys of "column[:type][:desc]".
+// column is index or header name (case insensitive). type is str, num, size or time,
+// and is guessed by kind of the column if omitted.
+func parseSortKeys(s string, header []string, kind func(int) ValueKind) ([]sortKey, error) {
+	keys := make([]sortKey, 0)
+	for _, spec := range strings.Split(s, ",") {
+		opts := strings.Split(strings.TrimSpace(spec), ":")
+		index, err := columnIndex(opts[0], header)
+		if err != nil {
+			return nil, err
+		}
+		key := sortKey{index: index}
+		switch kind(index) {
+		case NumberKind:
+			key.typ = sortNumber
+		case TimeKind:
+			key.typ = sortTime
+		}
+		for _, opt := range opts[1:] {
+			opt = strings.ToLower(opt)
+			typ, ok := sortTypes[opt]
+			switch {
+			case ok:
+				key.typ = typ
+			case opt == "asc":
+				key.desc = false
+			case opt == "desc":
+				key.desc = true
+			default:
+				return nil, fmt.Errorf("Unknown sort option. [%s] (str, num, size, time, asc, desc)", spec)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// columnIndex returns index of column number or header name (case insensitive).
+func columnIndex(col string, header []string) (int, error) {
+	col = strings.TrimSpace(col)
+	if i, err := strconv.Atoi(col); err == nil {
+		if i < 0 || i >= len(header) {
+			return 0, fmt.Errorf("Column number out of range. [%d] (0-%d)", i, len(header)-1)
+		}
+		return i, nil
+	}
+	for i, name := range header {
+		if strings.EqualFold(name, col) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown column. [%s] (%s)", col, strings.Join(header, ", "))
+}
+
+// sortRecords sorts rs by keys stably.
+func sortRecords(rs records, keys []sortKey) {
+	vals := make([][]sortValue, len(rs))
+	for i, r := range rs {
+		vals[i] = make([]sortValue, len(keys))
+		for j, key := range keys {
+			vals[i][j] = key.value(r)
+		}
+	}
+	sort.Stable(&recordSorter{rs: rs, vals: vals, keys: keys})
+}
+
+// value returns parsed value of record r.
+func (k sortKey) value(r []string) sortValue {
+	var (
+		v   = sortValue{}
+		err error
+	)
+	if k.index >= len(r) {
+		return v
+	}
+	v.s = r[k.index]
+	switch k.typ {
+	case sortNumber:
+		v.n, err = strconv.ParseFloat(v.s, 64)
+	case sortSize:
+		var n int64
+		n, err = parseSize(v.s)
+		v.n = float64(n)
+	case sortTime:
+		v.t, err = parseTime(v.s)
+	}
+	v.ok = err == nil
+	return v
+}
+
+// compare returns -1, 0 or 1 of a and b in key order. Unparsable values are last.
+func (k sortKey) compare(a, b sortValue) int {
+	var c int
+	switch {
+	case k.typ != sortString && a.ok != b.ok:
+		if a.ok {
+			return -1
+		}
+		return 1
+	case k.typ == sortString || !a.ok:
+		c = strings.Compare(a.s, b.s)
+	case k.typ == sortTime:
+		switch {
+		case a.t.Before(b.t):
+			c = -1
+		case a.t.After(b.t):
+			c = 1
+		}
+	case a.n < b.n:
+		c = -1
+	case a.n > b.n:
+		c = 1
+	}
+	if k.desc {
+		return -c
+	}
+	return c
+}
+
+// Len returns records length.
+func (s *recordSorter) Len() int {
+	return len(s.rs)
+}
+
+// Less returns which record is less.
+func (s *recordSorter) Less(i, j int) bool {
+	for k, key := range s.keys {
+		if c := key.compare(s.vals[i][k], s.vals[j][k]); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+// Swap is records swap func.
+func (s *recordSorter) Swap(i, j int) {
+	s.rs[i], s.rs[j] = s.rs[j], s.rs[i]
+	s.vals[i], s.vals[j] = s.vals[j], s.vals[i]
+}
@@ -0,0 +1,72 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSortRecords is test sortRecords with typed keys.
+func TestSortRecords(t *testing.T) {
+
+	defer func() { timeLoc = time.Local }()
+	timeLoc = time.UTC
+
+	header := []string{"Rel", "Size", "Time", "Human"}
+	kind := func(i int) ValueKind { return kindOf(header[i]) }
+	rs := func() records {
+		return records{
+			{"b", "9", "2017/03/25 10:00:00.000", "1M"},
+			{"a", "100", "2017/03/25 09:00:00.000", "10K"},
+			{"c", "", "2017-03-25T19:30:00+09:00", "2G"},
+			{"d", "9", "2017/03/25 11:00:00.000", "x"},
+		}
+	}
+	cases := []struct {
+		sorts    string
+		expected []string
+	}{
+		{"0", []string{"a", "b", "c", "d"}},
+		{"Size", []string{"b", "d", "a", "c"}},
+		{"1:str", []string{"c", "a", "b", "d"}},
+		{"size:desc,rel:desc", []string{"a", "d", "b", "c"}},
+		{"Time", []string{"a", "b", "c", "d"}},
+		{"2:str", []string{"c", "a", "b", "d"}},
+		{"human:size:desc", []string{"c", "b", "a", "d"}},
+	}
+	for _, tc := range cases {
+		keys, err := parseSortKeys(tc.sorts, header, kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := rs()
+		sortRecords(r, keys)
+		rels := make([]string, 0)
+		for _, v := range r {
+			rels = append(rels, v[0])
+		}
+		if fmt.Sprint(rels) != fmt.Sprint(tc.expected) {
+			t.Errorf("[%s]: Expected %v but actual: %v\n", tc.sorts, tc.expected, rels)
+		}
+	}
+
+	for _, s := range []string{"4", "-1", "Name", "0:up", ""} {
+		if _, err := parseSortKeys(s, header, kind); err == nil {
+			t.Errorf("[%s]: Expected error but nil\n", s)
+		}
+	}
+}
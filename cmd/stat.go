@@ -0,0 +1,109 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+)
+
+// idNames caches user and group names by id.
+type idNames struct {
+	sync.Mutex
+	m map[string]string
+}
+
+var (
+	userNames  = &idNames{m: make(map[string]string)}
+	groupNames = &idNames{m: make(map[string]string)}
+)
+
+// setOwner sets owner information of f to fi if the platform supports.
+func setOwner(fi *FileInfo, f os.FileInfo) {
+	uid, gid, ok := fileOwner(f)
+	if !ok {
+		return
+	}
+	fi.UID = uid
+	fi.GID = gid
+	fi.Owner = userNames.lookup(uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+		return u.Username, nil
+	})
+	fi.Group = groupNames.lookup(gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+		return g.Name, nil
+	})
+}
+
+// setInode sets device, inode and number of links of f to fi if the platform supports.
+func setInode(fi *FileInfo, f os.FileInfo) {
+	dev, ino, nlink, ok := fileID(f)
+	if !ok {
+		return
+	}
+	fi.Dev = fmt.Sprint(dev)
+	fi.Inode = fmt.Sprint(ino)
+	fi.Nlink = fmt.Sprint(nlink)
+}
+
+// setDiskUsage sets allocated disk size of f to fi if the platform supports.
+func setDiskUsage(fi *FileInfo, f os.FileInfo) {
+	usage, ok := fileDiskUsage(f)
+	if !ok {
+		return
+	}
+	fi.DiskUsage = fmt.Sprint(usage)
+}
+
+// hardLinkKey returns device and inode key of f if f has other hard links.
+func hardLinkKey(f os.FileInfo) (string, bool) {
+	dev, ino, nlink, ok := fileID(f)
+	if !ok || nlink < 2 || f.IsDir() {
+		return "", false
+	}
+	return fmt.Sprintf("%d:%d", dev, ino), true
+}
+
+// lookup returns cached name of id. Empty if not found. (id is in UID or GID column)
+func (n *idNames) lookup(id string, find func(string) (string, error)) string {
+	n.Lock()
+	defer n.Unlock()
+	if name, ok := n.m[id]; ok {
+		return name
+	}
+	name, err := find(id)
+	if err != nil {
+		name = ""
+	}
+	n.m[id] = name
+	return name
+}
+
+// setTimes sets access, change and birth time of f to fi. Unsupported times are empty.
+func setTimes(fi *FileInfo, path string, f os.FileInfo, birth bool) {
+	atime, ctime, btime := fileTimes(path, f, birth)
+	fi.ATime = formatTime(atime)
+	fi.CTime = formatTime(ctime)
+	fi.BTime = formatTime(btime)
+}
@@ -0,0 +1,39 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns access, change and birth time of f.
+// Birth time is zero if the filesystem doesn't provide.
+func fileTimes(path string, f os.FileInfo, birth bool) (atime, ctime, btime time.Time) {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	atime = time.Unix(int64(st.Atimespec.Sec), int64(st.Atimespec.Nsec))
+	ctime = time.Unix(int64(st.Ctimespec.Sec), int64(st.Ctimespec.Nsec))
+	if birth && st.Birthtimespec.Sec > 0 {
+		btime = time.Unix(int64(st.Birthtimespec.Sec), int64(st.Birthtimespec.Nsec))
+	}
+	return
+}
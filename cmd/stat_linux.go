@@ -0,0 +1,43 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileTimes returns access, change and birth time of f.
+// Birth time is read by statx only if birth, and zero if the filesystem doesn't provide.
+func fileTimes(path string, f os.FileInfo, birth bool) (atime, ctime, btime time.Time) {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	atime = time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	ctime = time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec))
+	if !birth {
+		return
+	}
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW, unix.STATX_BTIME, &stx)
+	if err == nil && stx.Mask&unix.STATX_BTIME != 0 {
+		btime = time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+	}
+	return
+}
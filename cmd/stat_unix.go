@@ -0,0 +1,51 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// fileOwner returns uid and gid of f.
+func fileOwner(f os.FileInfo) (string, string, bool) {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", "", false
+	}
+	return fmt.Sprint(st.Uid), fmt.Sprint(st.Gid), true
+}
+
+// fileID returns device, inode and number of hard links of f.
+func fileID(f os.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), uint64(st.Nlink), true
+}
+
+// fileDiskUsage returns allocated size of 512 byte blocks.
+func fileDiskUsage(f os.FileInfo) (int64, bool) {
+	st, ok := f.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int64(st.Blocks) * 512, true
+}
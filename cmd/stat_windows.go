@@ -0,0 +1,52 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileOwner is not supported on windows.
+func fileOwner(f os.FileInfo) (string, string, bool) {
+	return "", "", false
+}
+
+// fileID is not supported on windows.
+func fileID(f os.FileInfo) (dev, ino, nlink uint64, ok bool) {
+	return 0, 0, 0, false
+}
+
+// fileDiskUsage is not supported on windows.
+func fileDiskUsage(f os.FileInfo) (int64, bool) {
+	return 0, false
+}
+
+// fileTimes returns access and creation (birth) time of f. Change time is not supported.
+func fileTimes(path string, f os.FileInfo, birth bool) (atime, ctime, btime time.Time) {
+	d, ok := f.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return
+	}
+	atime = time.Unix(0, d.LastAccessTime.Nanoseconds())
+	if birth {
+		btime = time.Unix(0, d.CreationTime.Nanoseconds())
+	}
+	return
+}
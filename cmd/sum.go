@@ -15,16 +15,20 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
-	"path/filepath"
 	"regexp"
 	"runtime"
-	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"unicode/utf8"
 
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
@@ -35,10 +39,24 @@ import (
 
 var (
 	// Cmd options.
-	del            string
-	keyCol, valCol int
+	del        string
+	keyCol     string
+	valCols    []string
+	agg        string
+	sumTotal   bool
+	sumDelta   bool
+	sumPercent bool
+	lenient    bool
+
+	// commaCandidates are delimiters detected from header in priority order.
+	commaCandidates = []rune{',', '\t', ';', '|'}
+	// utf8BOM is byte order mark of UTF-8.
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
 )
 
+// sampleSize is size of csv head to detect encoding and delimiter.
+const sampleSize = 64 * 1024
+
 // sumCmd represents the sum command
 var sumCmd = &cobra.Command{
 	Use:   "sum path/to/dir",
@@ -47,48 +65,74 @@ var sumCmd = &cobra.Command{
 For example:
 
 	gfi sum -k 0 -v 2 path/to/one.csv path/to/two.csv
+	gfi sum -k 7 -v 5 --agg sum -D , path/to/one.csv path/to/two.csv
+	gfi sum -k Rel -v Size,FileCount --delta --percent path/to/one.csv path/to/two.csv
+	gfi sum -k Rel -v Size --lenient path/to/one.csv path/to/broken.csv
 
 `,
 	Run: executeSum,
 }
 
+// sumInput is csv reader with its key and value column indexes.
+type sumInput struct {
+	path    string
+	reader  *csv.Reader
+	keyIdx  int
+	valIdxs []int
+	// skipped is count of malformed rows skipped with --lenient.
+	skipped int
+}
+
 type line struct {
-	index int
-	key   string
-	value string
+	index  int
+	row    int
+	key    string
+	values []string
 }
 
 func init() {
 	RootCmd.AddCommand(sumCmd)
 
 	// Key column number..
-	sumCmd.Flags().IntVarP(&keyCol, "key", "k", 0, "Key column number (default is 0)")
-	// Value column number.
-	sumCmd.Flags().IntVarP(&valCol, "val", "v", 1, "Value column number (default is 1)")
+	sumCmd.Flags().StringVarP(&keyCol, "key", "k", "0", "Key column number or header name")
+	// Value column numbers.
+	sumCmd.Flags().StringSliceVarP(&valCols, "val", "v", []string{"1"}, "Value column numbers or header names with comma separated")
 	// Csv delimiter.
-	sumCmd.Flags().StringVarP(&del, "delimiter", "D", "\t", "Csv delimiter. (default is TAB)")
+	sumCmd.Flags().StringVarP(&del, "delimiter", "D", "", "Csv delimiter (default is detected from header: comma, TAB, semicolon or pipe)")
 	// Sort with target column for csv.
-	sumCmd.Flags().StringVarP(&sorts, "sorts", "s", "0", "Sort target column number with commma sepalated (ex: 0,1,2)")
+	sumCmd.Flags().StringVarP(&sorts, "sorts", "s", "0", "Sort columns with comma separated. Column number or name with :num, :size, :time, :str or :desc (ex: 1:desc,0)")
+	// Aggregation function.
+	sumCmd.Flags().StringVarP(&agg, "agg", "a", AggLast, "Aggregation of values of the same key in a csv ("+strings.Join(aggNames, ", ")+")")
+	// Computed columns.
+	sumCmd.Flags().BoolVar(&sumTotal, "total", false, "Append Total column of values")
+	sumCmd.Flags().BoolVar(&sumDelta, "delta", false, "Append Delta column (last csv minus first csv)")
+	sumCmd.Flags().BoolVar(&sumPercent, "percent", false, "Append Percent column of change (Delta / first csv * 100)")
 	// Whether input csv in ShiftJIS encoding.
-	sumCmd.Flags().BoolVarP(&sjisIn, "sjisin", "J", false, "Input csv in ShiftJIS encoding")
+	sumCmd.Flags().BoolVarP(&sjisIn, "sjisin", "J", false, "Input csv in ShiftJIS encoding (default is detected)")
+	// Skip malformed rows.
+	sumCmd.Flags().BoolVar(&lenient, "lenient", false, "Skip malformed rows with warnings instead of error")
 }
 
 func executeSum(cmd *cobra.Command, args []string) {
 
 	var (
-		err error
-
-		match   *regexp.Regexp
-		ignore  *regexp.Regexp
-		keyName string
-
-		csvMap  = make(map[string][]string)
-		readers = make([]*csv.Reader, 0)
-		q       = make(chan line)
-		wg      = new(sync.WaitGroup)
-		sem     = make(chan struct{}, runtime.NumCPU())
+		err  error
+		keys []sortKey
+
+		match    *regexp.Regexp
+		ignore   *regexp.Regexp
+		keyName  string
+		valNames []string
+
+		csvMap = make(map[string][][]*aggregator)
+		inputs = make([]*sumInput, 0)
+		q      = make(chan line)
+		wg     = new(sync.WaitGroup)
+		sem    = make(chan struct{}, runtime.NumCPU())
 	)
 
+	cnt = 0
+
 	if len(args) == 0 {
 		cmd.Help()
 		return
@@ -106,28 +150,61 @@ func executeSum(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	err = validateAgg(agg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(valCols) == 0 {
+		valCols = []string{"1"}
+	}
+
 	// Load csv and store.
 	for _, csvPath := range args {
-		fmt.Println("Open:", csvPath)
+		fmt.Fprintln(os.Stderr, "Open:", csvPath)
 		c, err := os.Open(csvPath)
 		if err != nil {
 			log.Fatalln(err)
 		}
 		defer c.Close()
-		var reader *csv.Reader
-		if sjisIn {
-			reader = csv.NewReader(transform.NewReader(c, japanese.ShiftJIS.NewDecoder()))
-		} else {
-			reader = csv.NewReader(c)
+		reader, err := newSumReader(c)
+		if err != nil {
+			log.Fatalln(fmt.Errorf("%s (%s)", err, csvPath))
 		}
-		reader.Comma = []rune(del)[0]
 		// Get key name.
 		header, err := reader.Read()
-		keyName = header[keyCol]
+		if err == io.EOF {
+			log.Fatalln(fmt.Errorf("Empty csv. (%s)", csvPath))
+		}
 		if err != nil {
-			log.Fatalln(err)
+			log.Fatalln(fmt.Errorf("%s (%s)", err, csvPath))
 		}
-		readers = append(readers, reader)
+		// Columns are resolved by the header of each csv.
+		input := &sumInput{path: csvPath, reader: reader}
+		input.keyIdx, err = columnIndex(keyCol, header)
+		if err != nil {
+			log.Fatalln(fmt.Errorf("%s (%s)", err, csvPath))
+		}
+		keyName = header[input.keyIdx]
+		valNames = make([]string, 0, len(valCols))
+		for _, v := range valCols {
+			idx, err := columnIndex(v, header)
+			if err != nil {
+				log.Fatalln(fmt.Errorf("%s (%s)", err, csvPath))
+			}
+			input.valIdxs = append(input.valIdxs, idx)
+			valNames = append(valNames, header[idx])
+		}
+		inputs = append(inputs, input)
+	}
+
+	// Check sort keys. Value columns are typed by value column name.
+	if sorts == "" {
+		sorts = "0"
+	}
+	header, kinds := sumHeader(keyName, valNames, args)
+	keys, err = parseSortKeys(sorts, header, func(i int) ValueKind { return kinds[i] })
+	if err != nil {
+		log.Fatalln(err)
 	}
 
 	// Compile if given matches and ignores.
@@ -144,9 +221,9 @@ func executeSum(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	for i, reader := range readers {
+	for i, input := range inputs {
 		wg.Add(1)
-		go func(i int, r *csv.Reader) {
+		go func(i int, in *sumInput) {
 			sem <- struct{}{}
 			defer func() {
 				wg.Done()
@@ -156,16 +233,25 @@ func executeSum(cmd *cobra.Command, args []string) {
 			// Loop records.
 			for {
 
-				record, err := r.Read()
+				record, err := in.reader.Read()
 				if err == io.EOF {
 					break
 				}
+				if perr, ok := err.(*csv.ParseError); ok {
+					in.malformed(perr.Line, perr.Err)
+					continue
+				}
+				if err != nil {
+					log.Fatalln(fmt.Errorf("%s (%s)", err, in.path))
+				}
 
-				l := line{
-					index: i,
-					key:   record[keyCol],
-					value: record[valCol],
+				row, _ := in.reader.FieldPos(0)
+				l, err := in.line(i, record)
+				if err != nil {
+					in.malformed(row, err)
+					continue
 				}
+				l.row = row
 
 				// Check Ignore.
 				if ignore != nil && ignore.MatchString(l.key) {
@@ -178,7 +264,7 @@ func executeSum(cmd *cobra.Command, args []string) {
 				}
 				q <- l
 			}
-		}(i, reader)
+		}(i, input)
 	}
 
 	// Async wait.
@@ -193,60 +279,264 @@ func executeSum(cmd *cobra.Command, args []string) {
 		if !silent {
 			fmt.Fprintf(os.Stderr, "Count: %d\r", cnt)
 		}
-		if _, ok := csvMap[line.key]; ok {
-			csvMap[line.key][line.index] = line.value
-		} else {
-			s := make([]string, len(args))
-			s[line.index] = line.value
-			csvMap[line.key] = s
+		aggs, ok := csvMap[line.key]
+		if !ok {
+			aggs = make([][]*aggregator, len(args))
+			csvMap[line.key] = aggs
+		}
+		if aggs[line.index] == nil {
+			aggs[line.index] = make([]*aggregator, len(valCols))
+			for v := range valCols {
+				aggs[line.index][v] = &aggregator{name: agg}
+			}
+		}
+		for v, value := range line.values {
+			err = aggs[line.index][v].add(value)
+			if err != nil {
+				log.Fatalln(fmt.Errorf("%s (%s:%d)", err, args[line.index], line.row))
+			}
+		}
+	}
+
+	for _, in := range inputs {
+		if in.skipped != 0 {
+			fmt.Fprintf(os.Stderr, "Warning: Skipped [%d] malformed rows in [%s].\n", in.skipped, in.path)
 		}
 	}
 
 	if len(csvMap) == 0 {
-		fmt.Println("There is no output !")
+		fmt.Fprintln(os.Stderr, "There is no output !")
 		return
 	}
 
-	// Output to csv.
-	os.MkdirAll(filepath.Dir(out), os.ModePerm)
-	c, err := os.Create(out)
+	// Output header and records.
+	writer := newOutput(out, header, func(record []string, i int) ValueKind {
+		return kinds[i]
+	})
+
+	// map to array.
+	var csvArray records
+	for k, aggs := range csvMap {
+		csvArray = append(csvArray, sumRecord(k, aggs))
+	}
+
+	// sort
+	sortRecords(csvArray, keys)
+
+	for _, v := range csvArray {
+		err = writer.Write(v)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	err = writer.Close()
 	if err != nil {
 		log.Fatalln(err)
 	}
-	defer c.Close()
-	var writer *csv.Writer
-	if sjisOut {
-		writer = csv.NewWriter(transform.NewWriter(c, japanese.ShiftJIS.NewEncoder()))
-	} else {
-		writer = csv.NewWriter(c)
+	fmt.Fprintf(os.Stderr, "Write to [%s]. ([%d] row)\n", outName(), cnt)
+}
+
+// sumHeader returns output header and value kinds of columns.
+// Value columns are grouped by csv if multiple values.
+func sumHeader(keyName string, valNames, paths []string) ([]string, []ValueKind) {
+	header := []string{keyName}
+	kinds := []ValueKind{StringKind}
+	name := func(prefix, valName string) string {
+		if len(valNames) == 1 {
+			return prefix
+		}
+		return prefix + ":" + valName
 	}
-	writer.Comma = ','
-	writer.UseCRLF = true
 
-	// Write header.
-	err = writer.Write(append([]string{keyName}, args...))
+	for _, p := range paths {
+		for _, v := range valNames {
+			header = append(header, name(p, v))
+			kinds = append(kinds, aggKind(agg, kindOf(v)))
+		}
+	}
+	for _, c := range []struct {
+		name string
+		on   bool
+	}{{"Total", sumTotal}, {"Delta", sumDelta}, {"Percent", sumPercent}} {
+		if !c.on {
+			continue
+		}
+		for _, v := range valNames {
+			header = append(header, name(c.name, v))
+			kinds = append(kinds, NumberKind)
+		}
+	}
+	return header, kinds
+}
+
+// sumRecord returns output record of key with aggregated values of each csv and computed columns.
+// Computed values are empty if values are not numbers.
+func sumRecord(key string, aggs [][]*aggregator) []string {
+	record := []string{key}
+	results := make([][]string, len(valCols))
+	for _, a := range aggs {
+		for v := range valCols {
+			var r string
+			if a != nil {
+				r = a[v].result()
+			}
+			record = append(record, r)
+			results[v] = append(results[v], r)
+		}
+	}
+
+	format := func(n float64, ok bool) string {
+		if !ok {
+			return ""
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	if sumTotal {
+		for _, values := range results {
+			var total float64
+			ok := false
+			for _, value := range values {
+				if n, err := strconv.ParseFloat(value, 64); err == nil {
+					total += n
+					ok = true
+				}
+			}
+			record = append(record, format(total, ok))
+		}
+	}
+	if sumDelta {
+		for _, values := range results {
+			first, last, ok := firstLast(values)
+			record = append(record, format(last-first, ok))
+		}
+	}
+	if sumPercent {
+		for _, values := range results {
+			first, last, ok := firstLast(values)
+			record = append(record, format(math.Round((last-first)/first*10000)/100, ok && first != 0))
+		}
+	}
+	return record
+}
+
+// firstLast returns numbers of the first and the last values.
+func firstLast(values []string) (float64, float64, bool) {
+	first, err := strconv.ParseFloat(values[0], 64)
 	if err != nil {
-		log.Fatalln(err)
+		return 0, 0, false
 	}
+	last, err := strconv.ParseFloat(values[len(values)-1], 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return first, last, true
+}
 
-	// map to array.
-	var csvArray records
-	for k, v := range csvMap {
-		csvArray = append(csvArray, append([]string{k}, v...))
+// line returns line of record. Error if columns are missing or values are not numbers for numeric aggregation.
+func (in *sumInput) line(index int, record []string) (line, error) {
+	l := line{index: index}
+	if in.keyIdx >= len(record) {
+		return l, fmt.Errorf("Missing key column. [%d] (%d columns)", in.keyIdx, len(record))
+	}
+	l.key = record[in.keyIdx]
+	for _, v := range in.valIdxs {
+		if v >= len(record) {
+			return l, fmt.Errorf("Missing value column. [%d] (%d columns)", v, len(record))
+		}
+		if isNumericAgg(agg) {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(record[v]), 64); err != nil {
+				return l, fmt.Errorf("Not a number for %s. [%s]", agg, record[v])
+			}
+		}
+		l.values = append(l.values, record[v])
+	}
+	return l, nil
+}
+
+// malformed reports malformed row. The row is skipped with --lenient, otherwise exits.
+func (in *sumInput) malformed(row int, err error) {
+	if !lenient {
+		log.Fatalln(fmt.Errorf("%s:%d: %s", in.path, row, err))
 	}
+	in.skipped++
+	fmt.Fprintf(os.Stderr, "Warning: %s:%d: %s. skip.\n", in.path, row, err)
+}
 
-	// sort
-	if sorts == "" {
-		sorts = "0"
+// newSumReader returns csv reader of r with detected encoding and delimiter.
+// Input is ShiftJIS if --sjisin or not valid UTF-8. UTF-8 BOM and format marker line of get are skipped.
+func newSumReader(r io.Reader) (*csv.Reader, error) {
+	br := bufio.NewReaderSize(r, sampleSize)
+	sample, err := br.Peek(sampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
 	}
-	sort.Sort(csvArray)
+	partial := len(sample) == sampleSize
 
-	for _, v := range csvArray {
-		err = writer.Write(v)
+	if bytes.HasPrefix(sample, utf8BOM) {
+		br.Discard(len(utf8BOM))
+		sample = sample[len(utf8BOM):]
+	}
+	// Format marker line of get is not header.
+	if bytes.HasPrefix(sample, []byte(formatMarker)) {
+		if i := bytes.IndexByte(sample, '\n'); i >= 0 {
+			br.Discard(i + 1)
+			sample = sample[i+1:]
+		}
+	}
+	// Header line to detect delimiter.
+	head := sample
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+
+	var in io.Reader = br
+	if sjisIn || !validUTF8(sample, partial) {
+		in = transform.NewReader(br, japanese.ShiftJIS.NewDecoder())
+		head, _, err = transform.Bytes(japanese.ShiftJIS.NewDecoder(), head)
 		if err != nil {
-			log.Fatalln(err)
+			return nil, err
+		}
+	}
+
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	if del != "" {
+		reader.Comma = []rune(del)[0]
+	} else {
+		reader.Comma = detectComma(string(head))
+	}
+	return reader, nil
+}
+
+// detectComma returns the most frequent delimiter candidate out of quotes in header. Comma if none.
+func detectComma(header string) rune {
+	counts := make(map[rune]int)
+	quoted := false
+	for _, c := range header {
+		if c == '"' {
+			quoted = !quoted
+		} else if !quoted {
+			counts[c]++
+		}
+	}
+	comma := commaCandidates[0]
+	for _, c := range commaCandidates {
+		if counts[c] > counts[comma] {
+			comma = c
+		}
+	}
+	return comma
+}
+
+// validUTF8 returns whether b is valid UTF-8. The last rune may be cut if partial.
+func validUTF8(b []byte, partial bool) bool {
+	if !partial {
+		return utf8.Valid(b)
+	}
+	for i := 0; i < utf8.UTFMax && i < len(b); i++ {
+		if utf8.Valid(b[:len(b)-i]) {
+			return true
 		}
 	}
-	writer.Flush()
-	fmt.Printf("Write to [%s]. ([%d] row)\n", out, cnt)
+	return false
 }
@@ -0,0 +1,251 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// runSum runs sumCmd and returns output records.
+func runSum(t *testing.T, c string, args ...string) [][]string {
+	RootCmd.SetArgs(append([]string{"sum", "-o", c}, args...))
+	err := RootCmd.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// writeCsvs writes csv files of data to dir and returns paths.
+func writeCsvs(t *testing.T, dir string, data ...string) []string {
+	paths := make([]string, 0)
+	for i, d := range data {
+		p := filepath.Join(dir, fmt.Sprintf("in%d.csv", i))
+		err := ioutil.WriteFile(p, []byte(d), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// TestSumCmdRunAgg is test sumCmd.Run with aggregation functions.
+func TestSumCmdRunAgg(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { del, keyCol, valCols, agg, sorts = "", "0", nil, AggLast, "0" }()
+
+	paths := writeCsvs(t, tmp,
+		"Ext,Size\n.go,10\n.md,3\n.go,20\n.go,6\n",
+		"Ext,Size\n.md,4\n.go,1.5\n",
+	)
+
+	cases := []struct {
+		agg      string
+		expected string
+	}{
+		{AggLast, "[[Ext in0.csv in1.csv] [.go 6 1.5] [.md 3 4]]"},
+		{AggFirst, "[[Ext in0.csv in1.csv] [.go 10 1.5] [.md 3 4]]"},
+		{AggSum, "[[Ext in0.csv in1.csv] [.go 36 1.5] [.md 3 4]]"},
+		{AggCount, "[[Ext in0.csv in1.csv] [.go 3 1] [.md 1 1]]"},
+		{AggMin, "[[Ext in0.csv in1.csv] [.go 6 1.5] [.md 3 4]]"},
+		{AggMax, "[[Ext in0.csv in1.csv] [.go 20 1.5] [.md 3 4]]"},
+		{AggAvg, "[[Ext in0.csv in1.csv] [.go 12 1.5] [.md 3 4]]"},
+		{AggConcat, "[[Ext in0.csv in1.csv] [.go 10;20;6 1.5] [.md 3 4]]"},
+	}
+	c := filepath.Join(tmp, getCsv1)
+	for _, tc := range cases {
+		records := runSum(t, c, append([]string{"-D", ",", "--agg", tc.agg}, paths...)...)
+		for i := range records[0][1:] {
+			records[0][i+1] = filepath.Base(records[0][i+1])
+		}
+		if fmt.Sprint(records) != tc.expected {
+			t.Errorf("[%s]: Expected %s but actual: %v\n", tc.agg, tc.expected, records)
+		}
+		os.Remove(c)
+	}
+}
+
+// TestSumCmdRunVals is test sumCmd.Run with multiple values and computed columns.
+func TestSumCmdRunVals(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() {
+		del, keyCol, valCols, sorts, sumTotal, sumDelta, sumPercent = "", "0", nil, "0", false, false, false
+	}()
+
+	paths := writeCsvs(t, tmp,
+		"Rel,Size,FileCount\na,100,1\nb,50,2\n",
+		"Rel,Size,FileCount\na,150,1\nc,10,1\n",
+		"Rel,Size,FileCount\na,75,3\nb,50,4\n",
+	)
+
+	c := filepath.Join(tmp, getCsv1)
+	records := runSum(t, c, append([]string{"-D", ",", "-v", "1,2", "--total", "--delta", "--percent", "-s", "0:desc"}, paths...)...)
+	for i := range records[0] {
+		records[0][i] = strings.TrimPrefix(records[0][i], tmp+string(filepath.Separator))
+	}
+	expected := [][]string{
+		{"Rel", "in0.csv:Size", "in0.csv:FileCount", "in1.csv:Size", "in1.csv:FileCount", "in2.csv:Size", "in2.csv:FileCount",
+			"Total:Size", "Total:FileCount", "Delta:Size", "Delta:FileCount", "Percent:Size", "Percent:FileCount"},
+		{"c", "", "", "10", "1", "", "", "10", "1", "", "", "", ""},
+		{"b", "50", "2", "", "", "50", "4", "100", "6", "0", "2", "0", "100"},
+		{"a", "100", "1", "150", "1", "75", "3", "325", "5", "-25", "2", "-25", "200"},
+	}
+	if fmt.Sprint(records) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but actual: %v\n", expected, records)
+	}
+}
+
+// TestSumCmdRunNames is test sumCmd.Run with header names of different column layouts.
+func TestSumCmdRunNames(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { del, keyCol, valCols, sorts = "", "0", nil, "0" }()
+
+	paths := writeCsvs(t, tmp,
+		"Full,Rel,Size\n/x/a,a,100\n/x/b,b,9\n",
+		// Format marker line of get is skipped.
+		"#gfi-format 2\r\nSize,Rel\r\n150,a\r\n10,b\r\n",
+	)
+
+	c := filepath.Join(tmp, getCsv1)
+	records := runSum(t, c, append([]string{"-k", "rel", "-v", "Size", "-s", "Rel:desc"}, paths...)...)
+	expected := [][]string{{"Rel", paths[0], paths[1]}, {"b", "9", "10"}, {"a", "100", "150"}}
+	if fmt.Sprint(records) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but actual: %v\n", expected, records)
+	}
+	os.Remove(c)
+	valCols = nil
+
+	// Sort by value column of csv path.
+	records = runSum(t, c, append([]string{"-D", ",", "-k", "Rel", "-v", "2", "-s", paths[0]}, paths[0], paths[0])...)
+	expected = [][]string{{"Rel", paths[0], paths[0]}, {"b", "9", "9"}, {"a", "100", "100"}}
+	if fmt.Sprint(records) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but actual: %v\n", expected, records)
+	}
+}
+
+// TestSumCmdRunDetect is test sumCmd.Run with detected delimiters and encodings.
+func TestSumCmdRunDetect(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { keyCol, valCols, sorts = "0", nil, "0" }()
+
+	sjis, err := japanese.ShiftJIS.NewEncoder().String("名前,サイズ\nあ,1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := writeCsvs(t, tmp,
+		"名前\tサイズ\nあ\t2\n",
+		"\xEF\xBB\xBF名前;サイズ\nあ;3\n",
+		sjis,
+		"名前|\"x,y\"|サイズ\nあ|z|4\n",
+	)
+
+	c := filepath.Join(tmp, getCsv1)
+	records := runSum(t, c, append([]string{"-k", "名前", "-v", "サイズ"}, paths...)...)
+	expected := [][]string{{"名前", paths[0], paths[1], paths[2], paths[3]}, {"あ", "2", "3", "1", "4"}}
+	if fmt.Sprint(records) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but actual: %v\n", expected, records)
+	}
+}
+
+// TestSumCmdRunLenient is test sumCmd.Run skipping malformed rows with --lenient.
+func TestSumCmdRunLenient(t *testing.T) {
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { keyCol, valCols, agg, sorts, lenient = "0", nil, AggLast, "0", false }()
+
+	paths := writeCsvs(t, tmp,
+		"Rel,Size\na,1\nb\nc,x\nd,\"4\n",
+		"Rel,Size\na,2\nb,3\n",
+	)
+
+	c := filepath.Join(tmp, getCsv1)
+	records := runSum(t, c, append([]string{"--lenient", "-a", AggSum}, paths...)...)
+	expected := [][]string{{"Rel", paths[0], paths[1]}, {"a", "1", "2"}, {"b", "", "3"}}
+	if fmt.Sprint(records) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v but actual: %v\n", expected, records)
+	}
+}
+
+// TestDetectComma is test detectComma.
+func TestDetectComma(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected rune
+	}{
+		{"a,b,c", ','},
+		{"a\tb,c\td", '\t'},
+		{"a;b", ';'},
+		{"\"a;b;c\"|d", '|'},
+		{"a", ','},
+	}
+	for _, tt := range tests {
+		if actual := detectComma(tt.header); actual != tt.expected {
+			t.Errorf("Expected %q of [%s] but actual: %q\n", tt.expected, tt.header, actual)
+		}
+	}
+}
+
+// TestAggregator is test aggregator errors.
+func TestAggregator(t *testing.T) {
+	if err := validateAgg("median"); err == nil {
+		t.Error("Expected unknown aggregation error but nil")
+	}
+	a := &aggregator{name: AggSum}
+	if err := a.add("1K"); err == nil {
+		t.Error("Expected not a number error but nil")
+	}
+	if a.result() != "" {
+		t.Errorf("Expected empty result but actual: [%s]\n", a.result())
+	}
+	a = &aggregator{name: AggConcat}
+	if err := a.add("1K"); err != nil {
+		t.Fatal(err)
+	}
+	if a.result() != "1K" {
+		t.Errorf("Expected [1K] but actual: [%s]\n", a.result())
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Cmd options.
+	timeFormat string
+	timeZone   string
+
+	// timeLoc is time zone of output.
+	timeLoc = time.Local
+)
+
+// initTime checks time format and loads time zone.
+func initTime() error {
+	var err error
+
+	if strings.TrimSpace(timeFormat) == "" {
+		return fmt.Errorf("Empty time format")
+	}
+	switch timeZone {
+	case "", "Local":
+		timeLoc = time.Local
+	default:
+		timeLoc, err = time.LoadLocation(timeZone)
+		if err != nil {
+			return fmt.Errorf("Unknown time zone. [%s] (%s)", timeZone, err)
+		}
+	}
+	return nil
+}
+
+// formatTime returns t in time format and time zone. Empty if t is zero.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	t = t.In(timeLoc)
+	switch strings.ToLower(timeFormat) {
+	case RFC3339:
+		return t.Format(time.RFC3339Nano)
+	case UNIX:
+		return fmt.Sprint(t.Unix())
+	case UNIXNANO:
+		return fmt.Sprint(t.UnixNano())
+	}
+	return t.Format(timeFormat)
+}
+
+// parseTime parses value in any of supported time formats.
+// Numbers are unix epoch seconds, or nanoseconds if more than 12 digits.
+// Values without zone are in the time zone.
+func parseTime(value string) (time.Time, error) {
+	if isLayout(timeFormat) {
+		if t, err := time.ParseInLocation(timeFormat, value, timeLoc); err == nil {
+			return t, nil
+		}
+	}
+	if isNumber(value) {
+		return parseEpoch(value)
+	}
+	for _, layout := range []string{time.RFC3339Nano, timeLayout} {
+		if t, err := time.ParseInLocation(layout, value, timeLoc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Unknown time format. [%s]", value)
+}
+
+// parseEpoch parses unix epoch seconds with optional fraction, or nanoseconds if more than 12 digits.
+func parseEpoch(value string) (time.Time, error) {
+	sec, frac := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		sec, frac = value[:i], value[i+1:]
+	}
+	n, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if frac == "" && len(strings.TrimPrefix(sec, "-")) > 12 {
+		return time.Unix(0, n), nil
+	}
+	if len(frac) > 9 {
+		frac = frac[:9]
+	}
+	var nsec int64
+	if frac != "" {
+		nsec, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if strings.HasPrefix(sec, "-") {
+		nsec = -nsec
+	}
+	return time.Unix(n, nsec), nil
+}
+
+// isLayout returns whether format is Go layout, not format name.
+func isLayout(format string) bool {
+	switch strings.ToLower(format) {
+	case RFC3339, UNIX, UNIXNANO:
+		return false
+	}
+	return true
+}
+
+// isNumber returns whether s is decimal number.
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return s != "" && s != "." && strings.Count(s, ".") <= 1 && strings.Trim(s, ".0123456789") == ""
+}
+
+// differTime returns whether both values exist and differ as instants beyond the time tolerance.
+// Values are compared as strings if not parsable.
+func differTime(one, other string) bool {
+	if !differ(one, other) {
+		return false
+	}
+	oneTime, err := parseTime(one)
+	if err != nil {
+		return true
+	}
+	otherTime, err := parseTime(other)
+	if err != nil {
+		return true
+	}
+	return !withinTolerance(oneTime.Sub(otherTime))
+}
+
+// withinTolerance returns whether time difference d is within the time tolerance,
+// or one hour shift of DST if dst tolerance.
+func withinTolerance(d time.Duration) bool {
+	if d < 0 {
+		d = -d
+	}
+	if d <= timeTolerance {
+		return true
+	}
+	if !dstTolerance {
+		return false
+	}
+	d -= time.Hour
+	if d < 0 {
+		d = -d
+	}
+	return d <= timeTolerance
+}
+
+// timeKey returns value as comparable instant. value is returned if not parsable.
+func timeKey(value string) string {
+	t, err := parseTime(value)
+	if err != nil {
+		return value
+	}
+	return fmt.Sprint(t.UnixNano())
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseTime is test parseTime of each time format.
+func TestParseTime(t *testing.T) {
+
+	defer func() { timeFormat, timeZone, timeLoc = timeLayout, "Local", time.Local }()
+
+	timeZone = "Asia/Tokyo"
+	err := initTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es := time.Date(2017, 3, 25, 3, 0, 0, 123456789, time.UTC)
+	values := []string{
+		"2017-03-25T12:00:00.123456789+09:00",
+		"2017-03-25T03:00:00.123456789Z",
+		"1490410800.123456789",
+		"1490410800123456789",
+	}
+	for _, v := range values {
+		as, err := parseTime(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !as.Equal(es) {
+			t.Fatalf("[%s] Expected: [%v] but actual: [%v]\n", v, es, as)
+		}
+	}
+
+	// Default layout is in the time zone.
+	as, err := parseTime("2017/03/25 12:00:00.123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !as.Equal(es.Truncate(time.Millisecond)) {
+		t.Fatalf("Expected: [%v] but actual: [%v]\n", es, as)
+	}
+
+	// Format.
+	for format, ev := range map[string]string{
+		RFC3339:     "2017-03-25T12:00:00.123456789+09:00",
+		UNIX:        "1490410800",
+		UNIXNANO:    "1490410800123456789",
+		"15:04 MST": "12:00 JST",
+	} {
+		timeFormat = format
+		if av := formatTime(es); av != ev {
+			t.Fatalf("[%s] Expected: [%v] but actual: [%v]\n", format, ev, av)
+		}
+	}
+
+	// Compare as instants.
+	timeFormat = timeLayout
+	one := FileInfo{Time: "2017/03/25 12:00:00.000", Type: FILE}
+	other := FileInfo{Time: "2017-03-25T03:00:00Z", Type: FILE}
+	if diffs := compareFileInfo(one, other); len(diffs) != 0 {
+		t.Fatalf("Expected: no difference but actual: [%v]\n", diffs)
+	}
+}
+
+// TestDifferTime is test differTime with time tolerance.
+func TestDifferTime(t *testing.T) {
+
+	defer func() { timeTolerance, dstTolerance = 0, false }()
+
+	base := "2017-03-25T03:00:00Z"
+	cases := []struct {
+		other     string
+		tolerance time.Duration
+		dst       bool
+		expect    bool
+	}{
+		{"2017-03-25T03:00:00Z", 0, false, false},
+		{"2017-03-25T03:00:01Z", 0, false, true},
+		{"2017-03-25T03:00:01Z", 2 * time.Second, false, false},
+		{"2017-03-25T02:59:57Z", 2 * time.Second, false, true},
+		{"2017-03-25T04:00:01Z", 2 * time.Second, false, true},
+		{"2017-03-25T04:00:01Z", 2 * time.Second, true, false},
+		{"2017-03-25T02:00:00Z", 0, true, false},
+	}
+	for _, c := range cases {
+		timeTolerance, dstTolerance = c.tolerance, c.dst
+		if as := differTime(base, c.other); as != c.expect {
+			t.Fatalf("[%v] Expected: [%v] but actual: [%v]\n", c, c.expect, as)
+		}
+	}
+}
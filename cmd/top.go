@@ -0,0 +1,276 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"container/heap"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/yukimemi/core"
+)
+
+var (
+	// Cmd options.
+	topNum int
+	topBy  string
+)
+
+// topItem is a ranked row. fi is file information of the row if file.
+type topItem struct {
+	size int64
+	key  string
+	row  []string
+	fi   FileInfo
+}
+
+// topHeap is min heap of topItem. The root is the smallest ranked.
+type topHeap []topItem
+
+// topCmd represents the top command
+var topCmd = &cobra.Command{
+	Use:   "top path/to/dir",
+	Short: "Get largest files or directories",
+	Long: `Get top N largest files (or directories with -d) command.
+Only N rows and totals of directories being walked are kept.
+For example:
+
+	gfi top -n 20 path/to/dir
+	gfi top -d --by DiskUsage path/to/dir
+
+`,
+	Run: executeTop,
+}
+
+func init() {
+	RootCmd.AddCommand(topCmd)
+
+	// Number of rows.
+	topCmd.Flags().IntVarP(&topNum, "num", "n", 10, "Number of largest rows")
+	// Size column.
+	topCmd.Flags().StringVar(&topBy, "by", FileSize.String(), "Size column to rank (Size, DiskUsage)")
+	// Skip flag.
+	topCmd.Flags().BoolVarP(&errSkip, "err", "e", false, "Skip getting information on error")
+	// Glob filters.
+	addGlobFlags(topCmd)
+	// Output columns.
+	topCmd.Flags().StringSliceVarP(&columns, "columns", "c", nil, "Output column names with comma separated (same as get, or size with -d)")
+}
+
+func executeTop(cmd *cobra.Command, args []string) {
+
+	var (
+		err    error
+		header []string
+		algos  []string
+		items  chan topItem
+
+		h = make(topHeap, 0, topNum)
+	)
+
+	cnt = 0
+
+	if len(args) == 0 {
+		cmd.Help()
+		return
+	}
+	// Get glob file args.
+	args, err = core.GetGlobArgs(args)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if topNum <= 0 {
+		log.Fatalln(fmt.Errorf("Number must be positive. [%d]", topNum))
+	}
+	if !strings.EqualFold(topBy, FileSize.String()) && !strings.EqualFold(topBy, FileDiskUsage.String()) {
+		log.Fatalln(fmt.Errorf("Unknown size column. [%s]", topBy))
+	}
+
+	if dirOnly {
+		dirColumns, err = getDirColumns(columns)
+		header = getDirCsvHeader()
+		items = topDirItems(args)
+	} else {
+		fileColumns, err = getFileColumns(columns, nil)
+		algos = columnHashes(fileColumns)
+		header = getFileCsvHeader()
+		items = topFileItems(args)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Keep the largest topNum items.
+	for item := range items {
+		cnt++
+		if !silent {
+			fmt.Fprintf(os.Stderr, "Count: %d\r", cnt)
+		}
+		if h.Len() < topNum {
+			heap.Push(&h, item)
+		} else if h.less(h[0], item) {
+			h[0] = item
+			heap.Fix(&h, 0)
+		}
+	}
+
+	// Output largest first.
+	sort.Sort(sort.Reverse(h))
+	writer := newOutput(out, header, headerKinds(header))
+	err = writer.Open()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, item := range h {
+		// Hashes are computed for the largest files only.
+		if len(algos) != 0 {
+			err = hashFileInfo(&item.fi, algos)
+			if err != nil {
+				if !errSkip {
+					log.Fatalln(err)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: [%s]. continue.\n", err)
+			}
+			item.row = fileInfoToCsv(item.fi)
+		}
+		err = writer.Write(item.row)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if h.Len() == 0 {
+		fmt.Fprintln(os.Stderr, "There is no information to get.")
+	} else {
+		fmt.Fprintf(os.Stderr, "Write to [%s]. ([%d] row)\n", outName(), h.Len())
+	}
+}
+
+// topFileItems gets files of roots as topItem.
+func topFileItems(roots []string) chan topItem {
+	fi := make(chan FileInfo)
+	items := make(chan topItem)
+	wg := new(sync.WaitGroup)
+
+	for _, root := range roots {
+		wg.Add(1)
+		go func(root string) {
+			defer wg.Done()
+			err := getFileInfo(root, fi)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}(root)
+	}
+	go func() {
+		wg.Wait()
+		close(fi)
+	}()
+	go func() {
+		defer close(items)
+		for f := range fi {
+			if f.Type != FILE {
+				continue
+			}
+			items <- topItem{size: topSize(f.Size, f.DiskUsage), key: f.Full, row: fileInfoToCsv(f), fi: f}
+		}
+	}()
+	return items
+}
+
+// topDirItems gets directories of roots as topItem.
+func topDirItems(roots []string) chan topItem {
+	di := make(chan DirInfo)
+	items := make(chan topItem)
+	wg := new(sync.WaitGroup)
+
+	for _, root := range roots {
+		wg.Add(1)
+		go func(root string) {
+			defer wg.Done()
+			err := getDirInfo(root, di)
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}(root)
+	}
+	go func() {
+		wg.Wait()
+		close(di)
+	}()
+	go func() {
+		defer close(items)
+		for d := range di {
+			items <- topItem{size: topSize(d.Size, d.DiskUsage), key: d.Full, row: dirInfoToCsv(d)}
+		}
+	}()
+	return items
+}
+
+// topSize returns size or disk usage by --by. 0 if empty.
+func topSize(size, usage string) int64 {
+	v := size
+	if strings.EqualFold(topBy, FileDiskUsage.String()) {
+		v = usage
+	}
+	n, _ := strconv.ParseInt(v, 10, 64)
+	return n
+}
+
+// less returns whether a is ranked lower than b. Ties are ranked by path.
+func (h topHeap) less(a, b topItem) bool {
+	if a.size != b.size {
+		return a.size < b.size
+	}
+	return a.key > b.key
+}
+
+// Len returns topHeap length.
+func (h topHeap) Len() int {
+	return len(h)
+}
+
+// Less returns which topItem is ranked lower.
+func (h topHeap) Less(i, j int) bool {
+	return h.less(h[i], h[j])
+}
+
+// Swap is topHeap swap func.
+func (h topHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+// Push is heap.Interface push func.
+func (h *topHeap) Push(x interface{}) {
+	*h = append(*h, x.(topItem))
+}
+
+// Pop is heap.Interface pop func.
+func (h *topHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
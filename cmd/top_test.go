@@ -0,0 +1,87 @@
+// Copyright © 2017 yukimemi <yukimemi@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTopCmdRun is test topCmd.Run.
+func TestTopCmdRun(t *testing.T) {
+
+	var (
+		err error
+	)
+
+	tmp := setup()
+	t.Log(tmp)
+	defer shutdown(tmp)
+	defer func() { columns, dirOnly, topNum, excludes = nil, false, 10, nil }()
+
+	// dir0/file0 (300), dir1/file1 (200), file2 (100) and 100 small files.
+	sizes := map[string]int{
+		filepath.Join("dir0", "file0"): 300,
+		filepath.Join("dir1", "file1"): 200,
+		"file2":                        100,
+	}
+	for i := 0; i < 100; i++ {
+		sizes[fmt.Sprintf("small%d", i)] = i % 10
+	}
+	for p, size := range sizes {
+		err = ioutil.WriteFile(filepath.Join(tmp, p), []byte(strings.Repeat("x", size)), os.ModePerm)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		args     []string
+		expected [][]string
+	}{
+		{[]string{"-n", "3", "-c", "Name,Size"}, [][]string{{"Name", "Size"}, {"file0", "300"}, {"file1", "200"}, {"file2", "100"}}},
+		{[]string{"-n", "2", "-d", "-c", "Name,Size"}, [][]string{{"Name", "Size"}, {filepath.Base(tmp), fmt.Sprint(1050)}, {"dir0", "300"}}},
+		{[]string{"-n", "2", "-d", "-c", "Name,Size", "--exclude", "*.csv"}, [][]string{{"Name", "Size"}, {filepath.Base(tmp), fmt.Sprint(1050)}, {"dir0", "300"}}},
+		{[]string{"-n", "1", "-c", "Name,Hash"}, [][]string{{"Name", "SHA256"}, {"file0", fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Repeat("x", 300))))}}},
+	}
+	c := filepath.Join(tmp, getCsv1)
+	for _, tc := range cases {
+		os.Remove(c)
+		RootCmd.SetArgs(append(append([]string{"top", "-o", c}, tc.args...), tmp))
+		err = RootCmd.Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := os.Open(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(records) != fmt.Sprint(tc.expected) {
+			t.Errorf("%v: Expected [%v] but actual: [%v]\n", tc.args, tc.expected, records)
+		}
+		columns, dirOnly, excludes = nil, false, nil
+	}
+}
@@ -3,10 +3,12 @@ module github.com/yukimemi/gfi
 go 1.17
 
 require (
+	github.com/cespare/xxhash/v2 v2.1.2
 	github.com/spf13/cobra v0.0.0-20170314171253-7be4beda01ec
 	github.com/spf13/viper v0.0.0-20170315134309-84f94806c67f
 	github.com/yukimemi/core v0.0.0-20170311234008-b04fe0ed0fc1
 	github.com/yukimemi/file v0.0.0-20170318135141-4ee67f5845c0
+	golang.org/x/sys v0.1.0
 	golang.org/x/text v0.0.0-20170323194135-fc7fa097411d
 )
 
@@ -24,7 +26,6 @@ require (
 	github.com/spf13/jwalterweatherman v0.0.0-20170109133355-fa7ca7e836cf // indirect
 	github.com/spf13/pflag v0.0.0-20170325194822-d90f37a48761 // indirect
 	github.com/stretchr/testify v1.7.0 // indirect
-	golang.org/x/sys v0.0.0-20170325170518-afadfcc7779c // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v2 v2.0.0-20170208141851-a3f3340b5840 // indirect
 )